@@ -0,0 +1,37 @@
+package auth
+
+import "time"
+
+type RefreshToken struct {
+	ID        int
+	UserID    int
+	SessionID string `gorm:"index"`
+	TokenHash string `gorm:"uniqueIndex"`
+	ExpiresAt time.Time
+	RevokedAt *time.Time
+	// RotatedAt is set when the token was exchanged for a new pair, as
+	// opposed to revoked by a logout.
+	RotatedAt *time.Time
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+type TokenPair struct {
+	UserID                int
+	AccessToken           string
+	AccessTokenExpiresAt  time.Time
+	RefreshToken          string
+	RefreshTokenExpiresAt time.Time
+}
+
+type RevokedToken struct {
+	TokenID   string    `gorm:"primaryKey"`
+	ExpiresAt time.Time `gorm:"index"`
+	CreatedAt time.Time
+}
+
+type UserRevocation struct {
+	UserID        int `gorm:"primaryKey;autoIncrement:false"`
+	RevokedBefore time.Time
+	UpdatedAt     time.Time
+}
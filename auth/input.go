@@ -0,0 +1,5 @@
+package auth
+
+type RefreshTokenInput struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
@@ -0,0 +1,236 @@
+package auth
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/dgrijalva/jwt-go"
+)
+
+var ErrUnknownKey = errors.New("unknown signing key")
+
+// Key is one entry of a KeySet. Keys loaded from a public key only can
+// verify tokens but never sign them, which is how retired keys are kept
+// around until the tokens they signed expire.
+type Key struct {
+	ID         string
+	Algorithm  string
+	signingKey interface{}
+	verifyKey  interface{}
+}
+
+type KeySet struct {
+	activeID string
+	keys     map[string]Key
+}
+
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	Y   string `json:"y,omitempty"`
+}
+
+// ParseKey reads a PEM encoded RS256 or ES256 key, private or public, or a
+// raw HS256 secret.
+func ParseKey(id string, algorithm string, data []byte) (Key, error) {
+	key := Key{ID: id, Algorithm: algorithm}
+
+	switch algorithm {
+	case "HS256":
+		if len(data) < 32 {
+			return key, fmt.Errorf("key %s: HS256 secret must be at least 32 bytes", id)
+		}
+
+		key.signingKey = data
+		key.verifyKey = data
+	case "RS256":
+		privateKey, err := jwt.ParseRSAPrivateKeyFromPEM(data)
+		if err == nil {
+			key.signingKey = privateKey
+			key.verifyKey = &privateKey.PublicKey
+			break
+		}
+
+		publicKey, err := jwt.ParseRSAPublicKeyFromPEM(data)
+		if err != nil {
+			return key, fmt.Errorf("key %s: %w", id, err)
+		}
+
+		key.verifyKey = publicKey
+	case "ES256":
+		privateKey, err := jwt.ParseECPrivateKeyFromPEM(data)
+		if err == nil {
+			key.signingKey = privateKey
+			key.verifyKey = &privateKey.PublicKey
+			break
+		}
+
+		publicKey, err := jwt.ParseECPublicKeyFromPEM(data)
+		if err != nil {
+			return key, fmt.Errorf("key %s: %w", id, err)
+		}
+
+		key.verifyKey = publicKey
+	default:
+		return key, fmt.Errorf("key %s: unsupported algorithm %s", id, algorithm)
+	}
+
+	if ecKey, ok := key.verifyKey.(*ecdsa.PublicKey); ok && ecKey.Curve != elliptic.P256() {
+		return key, fmt.Errorf("key %s: ES256 requires a P-256 key", id)
+	}
+
+	return key, nil
+}
+
+func NewKeySet(activeID string, keys []Key) (*KeySet, error) {
+	keySet := &KeySet{activeID: activeID, keys: map[string]Key{}}
+
+	for _, key := range keys {
+		if _, ok := keySet.keys[key.ID]; ok {
+			return nil, fmt.Errorf("duplicate key id %s", key.ID)
+		}
+
+		keySet.keys[key.ID] = key
+	}
+
+	activeKey, ok := keySet.keys[activeID]
+	if !ok {
+		return nil, fmt.Errorf("active key %s is not configured", activeID)
+	}
+
+	if activeKey.signingKey == nil {
+		return nil, fmt.Errorf("active key %s has no private key", activeID)
+	}
+
+	return keySet, nil
+}
+
+// LoadKeySet builds a KeySet from a comma separated list of
+// "kid:algorithm:path" entries, e.g. "2023-01:RS256:/etc/bwastartup/jwt.pem".
+func LoadKeySet(spec string, activeID string) (*KeySet, error) {
+	var keys []Key
+
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		parts := strings.SplitN(entry, ":", 3)
+		if len(parts) != 3 {
+			return nil, fmt.Errorf("invalid key entry %q", entry)
+		}
+
+		data, err := os.ReadFile(parts[2])
+		if err != nil {
+			return nil, err
+		}
+
+		key, err := ParseKey(parts[0], parts[1], data)
+		if err != nil {
+			return nil, err
+		}
+
+		keys = append(keys, key)
+	}
+
+	return NewKeySet(activeID, keys)
+}
+
+// NewEphemeralKeySet generates a throwaway RS256 key. Tokens signed with it
+// stop validating when the process restarts, so it is only meant for local
+// development.
+func NewEphemeralKeySet() (*KeySet, error) {
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return nil, err
+	}
+
+	key := Key{
+		ID:         "ephemeral",
+		Algorithm:  "RS256",
+		signingKey: privateKey,
+		verifyKey:  &privateKey.PublicKey,
+	}
+
+	return NewKeySet(key.ID, []Key{key})
+}
+
+func (k *KeySet) sign(claim jwt.MapClaims) (string, error) {
+	key := k.keys[k.activeID]
+
+	token := jwt.NewWithClaims(jwt.GetSigningMethod(key.Algorithm), claim)
+	token.Header["kid"] = key.ID
+
+	return token.SignedString(key.signingKey)
+}
+
+func (k *KeySet) verificationKey(token *jwt.Token) (interface{}, error) {
+	keyID, _ := token.Header["kid"].(string)
+
+	key, ok := k.keys[keyID]
+	if !ok {
+		return nil, ErrUnknownKey
+	}
+
+	if token.Method.Alg() != key.Algorithm {
+		return nil, ErrInvalidToken
+	}
+
+	return key.verifyKey, nil
+}
+
+// JWKS lists the public half of every asymmetric key. HS256 secrets are
+// never published.
+func (k *KeySet) JWKS() JWKS {
+	jwks := JWKS{Keys: []JWK{}}
+
+	var keyIDs []string
+	for keyID := range k.keys {
+		keyIDs = append(keyIDs, keyID)
+	}
+	sort.Strings(keyIDs)
+
+	for _, keyID := range keyIDs {
+		key := k.keys[keyID]
+		jwk := JWK{Kid: key.ID, Use: "sig", Alg: key.Algorithm}
+
+		switch publicKey := key.verifyKey.(type) {
+		case *rsa.PublicKey:
+			jwk.Kty = "RSA"
+			jwk.N = base64.RawURLEncoding.EncodeToString(publicKey.N.Bytes())
+			jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(publicKey.E)).Bytes())
+		case *ecdsa.PublicKey:
+			size := (publicKey.Curve.Params().BitSize + 7) / 8
+			jwk.Kty = "EC"
+			jwk.Crv = publicKey.Curve.Params().Name
+			jwk.X = base64.RawURLEncoding.EncodeToString(publicKey.X.FillBytes(make([]byte, size)))
+			jwk.Y = base64.RawURLEncoding.EncodeToString(publicKey.Y.FillBytes(make([]byte, size)))
+		default:
+			continue
+		}
+
+		jwks.Keys = append(jwks.Keys, jwk)
+	}
+
+	return jwks
+}
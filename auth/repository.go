@@ -0,0 +1,74 @@
+package auth
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type Repository interface {
+	SaveRefreshToken(token RefreshToken) (RefreshToken, error)
+	FindRefreshTokenByHash(hash string) (RefreshToken, error)
+	RotateRefreshToken(ID int) (bool, error)
+	RevokeRefreshTokensByUserID(userID int) error
+	RevokeRefreshTokensBySessionID(sessionID string) error
+}
+
+type repository struct {
+	db *gorm.DB
+}
+
+func NewRepository(db *gorm.DB) *repository {
+	return &repository{db}
+}
+
+func (r *repository) SaveRefreshToken(token RefreshToken) (RefreshToken, error) {
+	err := r.db.Create(&token).Error
+	if err != nil {
+		return token, err
+	}
+
+	return token, nil
+}
+
+func (r *repository) FindRefreshTokenByHash(hash string) (RefreshToken, error) {
+	var token RefreshToken
+
+	err := r.db.Where("token_hash = ?", hash).Find(&token).Error
+	if err != nil {
+		return token, err
+	}
+
+	return token, nil
+}
+
+// RotateRefreshToken only succeeds for a token that is still active, so two
+// requests racing to rotate the same refresh token cannot both win.
+func (r *repository) RotateRefreshToken(ID int) (bool, error) {
+	now := time.Now()
+
+	result := r.db.Model(&RefreshToken{}).Where("id = ? AND revoked_at IS NULL", ID).Updates(map[string]interface{}{"revoked_at": now, "rotated_at": now})
+	if result.Error != nil {
+		return false, result.Error
+	}
+
+	return result.RowsAffected == 1, nil
+}
+
+func (r *repository) RevokeRefreshTokensByUserID(userID int) error {
+	err := r.db.Model(&RefreshToken{}).Where("user_id = ? AND revoked_at IS NULL", userID).Update("revoked_at", time.Now()).Error
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (r *repository) RevokeRefreshTokensBySessionID(sessionID string) error {
+	err := r.db.Model(&RefreshToken{}).Where("session_id = ? AND revoked_at IS NULL", sessionID).Update("revoked_at", time.Now()).Error
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,130 @@
+package auth
+
+import (
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// RevocationStore remembers access tokens that were ended before they
+// expired, either one token at a time (keyed by its jti) or every token of a
+// user issued before a point in time.
+type RevocationStore interface {
+	RevokeToken(tokenID string, expiresAt time.Time) error
+	IsTokenRevoked(tokenID string) (bool, error)
+	RevokeUserTokens(userID int, issuedBefore time.Time) error
+	UserTokensRevokedBefore(userID int) (time.Time, error)
+}
+
+type memoryRevocationStore struct {
+	mu     sync.Mutex
+	tokens map[string]time.Time
+	users  map[int]time.Time
+}
+
+func NewMemoryRevocationStore() *memoryRevocationStore {
+	return &memoryRevocationStore{
+		tokens: map[string]time.Time{},
+		users:  map[int]time.Time{},
+	}
+}
+
+func (s *memoryRevocationStore) RevokeToken(tokenID string, expiresAt time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	for id, tokenExpiresAt := range s.tokens {
+		if now.After(tokenExpiresAt) {
+			delete(s.tokens, id)
+		}
+	}
+
+	s.tokens[tokenID] = expiresAt
+	return nil
+}
+
+func (s *memoryRevocationStore) IsTokenRevoked(tokenID string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, ok := s.tokens[tokenID]
+	return ok, nil
+}
+
+func (s *memoryRevocationStore) RevokeUserTokens(userID int, issuedBefore time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.users[userID] = issuedBefore
+	return nil
+}
+
+func (s *memoryRevocationStore) UserTokensRevokedBefore(userID int) (time.Time, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.users[userID], nil
+}
+
+type postgresRevocationStore struct {
+	db *gorm.DB
+}
+
+func NewPostgresRevocationStore(db *gorm.DB) *postgresRevocationStore {
+	return &postgresRevocationStore{db}
+}
+
+func (s *postgresRevocationStore) RevokeToken(tokenID string, expiresAt time.Time) error {
+	err := s.db.Where("expires_at < ?", time.Now()).Delete(&RevokedToken{}).Error
+	if err != nil {
+		return err
+	}
+
+	revokedToken := RevokedToken{TokenID: tokenID, ExpiresAt: expiresAt}
+
+	err = s.db.Clauses(clause.OnConflict{DoNothing: true}).Create(&revokedToken).Error
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (s *postgresRevocationStore) IsTokenRevoked(tokenID string) (bool, error) {
+	var count int64
+
+	err := s.db.Model(&RevokedToken{}).Where("token_id = ?", tokenID).Count(&count).Error
+	if err != nil {
+		return false, err
+	}
+
+	return count > 0, nil
+}
+
+func (s *postgresRevocationStore) RevokeUserTokens(userID int, issuedBefore time.Time) error {
+	revocation := UserRevocation{UserID: userID, RevokedBefore: issuedBefore}
+
+	err := s.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"revoked_before", "updated_at"}),
+	}).Create(&revocation).Error
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (s *postgresRevocationStore) UserTokensRevokedBefore(userID int) (time.Time, error) {
+	var revocation UserRevocation
+
+	err := s.db.Where("user_id = ?", userID).Find(&revocation).Error
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	return revocation.RevokedBefore, nil
+}
@@ -1,53 +1,237 @@
 package auth
 
 import (
+	"bwastartup/helper"
 	"errors"
+	"time"
 
 	"github.com/dgrijalva/jwt-go"
 )
 
+var (
+	ErrInvalidToken        = errors.New("invalid token")
+	ErrTokenExpired        = errors.New("token has expired")
+	ErrTokenRevoked        = errors.New("token has been revoked")
+	ErrInvalidRefreshToken = errors.New("invalid refresh token")
+	ErrRefreshTokenExpired = errors.New("refresh token has expired")
+)
+
 type Service interface {
-	GenerateToken(userID int) (string, error)
+	GenerateTokenPair(userID int) (TokenPair, error)
+	RefreshToken(refreshToken string) (TokenPair, error)
 	ValidateToken(token string) (*jwt.Token, error)
+	RevokeToken(token *jwt.Token) error
+	RevokeAllTokens(userID int) error
+	JWKS() JWKS
+}
+
+type Config struct {
+	AccessTokenTTL  time.Duration
+	RefreshTokenTTL time.Duration
 }
 
 type jwtService struct {
+	repository      Repository
+	revocationStore RevocationStore
+	keySet          *KeySet
+	config          Config
 }
 
-func NewService() *jwtService {
-	return &jwtService{}
+func NewService(repository Repository, revocationStore RevocationStore, keySet *KeySet, config Config) *jwtService {
+	return &jwtService{repository, revocationStore, keySet, config}
 }
 
-var SECRET_KEY = []byte("BWASTARTUP_s3cr3T_k3Y")
+func (s *jwtService) GenerateTokenPair(userID int) (TokenPair, error) {
+	sessionID, err := helper.RandomToken(16)
+	if err != nil {
+		return TokenPair{}, err
+	}
 
-func (s *jwtService) GenerateToken(userId int) (string, error) {
-	claim := jwt.MapClaims{}
-	claim["user_id"] = userId
+	return s.generateTokenPair(userID, sessionID)
+}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claim)
+// RefreshToken exchanges a refresh token for a new token pair. Every refresh
+// token can be used once; presenting one that was already rotated means it
+// leaked, so every refresh token of that user is revoked. A token revoked by
+// a logout is just invalid, clients may well send it again.
+func (s *jwtService) RefreshToken(refreshToken string) (TokenPair, error) {
+	storedToken, err := s.repository.FindRefreshTokenByHash(helper.HashToken(refreshToken))
+	if err != nil {
+		return TokenPair{}, err
+	}
 
-	signedToken, err := token.SignedString(SECRET_KEY)
+	if storedToken.ID == 0 {
+		return TokenPair{}, ErrInvalidRefreshToken
+	}
+
+	if storedToken.RotatedAt != nil {
+		err := s.repository.RevokeRefreshTokensByUserID(storedToken.UserID)
+		if err != nil {
+			return TokenPair{}, err
+		}
+
+		return TokenPair{}, ErrInvalidRefreshToken
+	}
+
+	if storedToken.RevokedAt != nil {
+		return TokenPair{}, ErrInvalidRefreshToken
+	}
+
+	if time.Now().After(storedToken.ExpiresAt) {
+		return TokenPair{}, ErrRefreshTokenExpired
+	}
+
+	rotated, err := s.repository.RotateRefreshToken(storedToken.ID)
 	if err != nil {
-		return signedToken, err
+		return TokenPair{}, err
 	}
 
-	return signedToken, nil
+	if !rotated {
+		return TokenPair{}, ErrInvalidRefreshToken
+	}
+
+	return s.generateTokenPair(storedToken.UserID, storedToken.SessionID)
 }
 
 func (s *jwtService) ValidateToken(encodedToken string) (*jwt.Token, error) {
-	token, err := jwt.Parse(encodedToken, func(token *jwt.Token) (interface{}, error) {
-		_, ok := token.Method.(*jwt.SigningMethodHMAC)
+	token, err := jwt.Parse(encodedToken, s.keySet.verificationKey)
 
-		if !ok {
-			return nil, errors.New("invalid token")
+	if err != nil {
+		validationError, ok := err.(*jwt.ValidationError)
+		if ok && validationError.Errors&jwt.ValidationErrorExpired != 0 {
+			return token, ErrTokenExpired
 		}
 
-		return []byte(SECRET_KEY), nil
-	})
+		return token, err
+	}
+
+	claim, ok := token.Claims.(jwt.MapClaims)
+	if !ok || !claim.VerifyExpiresAt(time.Now().Unix(), true) {
+		return token, ErrInvalidToken
+	}
+
+	tokenID, _ := claim["jti"].(string)
+	userID, _ := claim["user_id"].(float64)
+	issuedAt, _ := claim["iat"].(float64)
+
+	isRevoked, err := s.revocationStore.IsTokenRevoked(tokenID)
+	if err != nil {
+		return token, err
+	}
+
+	if isRevoked {
+		return token, ErrTokenRevoked
+	}
 
+	revokedBefore, err := s.revocationStore.UserTokensRevokedBefore(int(userID))
 	if err != nil {
 		return token, err
 	}
 
+	if int64(issuedAt) < revokedBefore.Unix() {
+		return token, ErrTokenRevoked
+	}
+
 	return token, nil
 }
+
+// RevokeToken ends the session the access token belongs to: the token itself
+// stops validating and its refresh tokens can no longer be rotated.
+func (s *jwtService) RevokeToken(token *jwt.Token) error {
+	claim, ok := token.Claims.(jwt.MapClaims)
+	if !ok {
+		return ErrInvalidToken
+	}
+
+	tokenID, _ := claim["jti"].(string)
+	sessionID, _ := claim["sid"].(string)
+	expiresAt, _ := claim["exp"].(float64)
+
+	err := s.revocationStore.RevokeToken(tokenID, time.Unix(int64(expiresAt), 0))
+	if err != nil {
+		return err
+	}
+
+	if sessionID != "" {
+		err := s.repository.RevokeRefreshTokensBySessionID(sessionID)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *jwtService) RevokeAllTokens(userID int) error {
+	err := s.revocationStore.RevokeUserTokens(userID, time.Now())
+	if err != nil {
+		return err
+	}
+
+	err = s.repository.RevokeRefreshTokensByUserID(userID)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (s *jwtService) JWKS() JWKS {
+	return s.keySet.JWKS()
+}
+
+func (s *jwtService) generateTokenPair(userID int, sessionID string) (TokenPair, error) {
+	tokens := TokenPair{UserID: userID}
+
+	accessToken, accessTokenExpiresAt, err := s.generateAccessToken(userID, sessionID)
+	if err != nil {
+		return tokens, err
+	}
+
+	refreshToken, err := helper.RandomToken(32)
+	if err != nil {
+		return tokens, err
+	}
+
+	storedToken := RefreshToken{}
+	storedToken.UserID = userID
+	storedToken.SessionID = sessionID
+	storedToken.TokenHash = helper.HashToken(refreshToken)
+	storedToken.ExpiresAt = time.Now().Add(s.config.RefreshTokenTTL)
+
+	storedToken, err = s.repository.SaveRefreshToken(storedToken)
+	if err != nil {
+		return tokens, err
+	}
+
+	tokens.AccessToken = accessToken
+	tokens.AccessTokenExpiresAt = accessTokenExpiresAt
+	tokens.RefreshToken = refreshToken
+	tokens.RefreshTokenExpiresAt = storedToken.ExpiresAt
+
+	return tokens, nil
+}
+
+func (s *jwtService) generateAccessToken(userID int, sessionID string) (string, time.Time, error) {
+	tokenID, err := helper.RandomToken(16)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	now := time.Now()
+	expiresAt := now.Add(s.config.AccessTokenTTL)
+
+	claim := jwt.MapClaims{}
+	claim["user_id"] = userID
+	claim["jti"] = tokenID
+	claim["sid"] = sessionID
+	claim["iat"] = now.Unix()
+	claim["exp"] = expiresAt.Unix()
+
+	signedToken, err := s.keySet.sign(claim)
+	if err != nil {
+		return signedToken, expiresAt, err
+	}
+
+	return signedToken, expiresAt, nil
+}
@@ -0,0 +1,199 @@
+package auth
+
+import (
+	"testing"
+	"time"
+)
+
+// memoryRepository keeps refresh tokens in memory, with the same
+// conditional updates as the database repository.
+type memoryRepository struct {
+	tokens []RefreshToken
+}
+
+func (r *memoryRepository) SaveRefreshToken(token RefreshToken) (RefreshToken, error) {
+	token.ID = len(r.tokens) + 1
+	r.tokens = append(r.tokens, token)
+
+	return token, nil
+}
+
+func (r *memoryRepository) FindRefreshTokenByHash(hash string) (RefreshToken, error) {
+	for _, token := range r.tokens {
+		if token.TokenHash == hash {
+			return token, nil
+		}
+	}
+
+	return RefreshToken{}, nil
+}
+
+func (r *memoryRepository) RotateRefreshToken(ID int) (bool, error) {
+	for i := range r.tokens {
+		if r.tokens[i].ID == ID && r.tokens[i].RevokedAt == nil {
+			now := time.Now()
+			r.tokens[i].RevokedAt = &now
+			r.tokens[i].RotatedAt = &now
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+func (r *memoryRepository) RevokeRefreshTokensByUserID(userID int) error {
+	return r.revoke(func(token RefreshToken) bool { return token.UserID == userID })
+}
+
+func (r *memoryRepository) RevokeRefreshTokensBySessionID(sessionID string) error {
+	return r.revoke(func(token RefreshToken) bool { return token.SessionID == sessionID })
+}
+
+func (r *memoryRepository) revoke(match func(token RefreshToken) bool) error {
+	now := time.Now()
+	for i := range r.tokens {
+		if match(r.tokens[i]) && r.tokens[i].RevokedAt == nil {
+			r.tokens[i].RevokedAt = &now
+		}
+	}
+
+	return nil
+}
+
+func newTestService(t *testing.T) (*jwtService, *memoryRepository) {
+	keySet, err := NewEphemeralKeySet()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	repository := &memoryRepository{}
+	service := NewService(repository, NewMemoryRevocationStore(), keySet, Config{AccessTokenTTL: time.Minute, RefreshTokenTTL: time.Hour})
+
+	return service, repository
+}
+
+func TestRefreshTokenRotation(t *testing.T) {
+	service, _ := newTestService(t)
+
+	first, err := service.GenerateTokenPair(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	second, err := service.RefreshToken(first.RefreshToken)
+	if err != nil {
+		t.Fatalf("rotating a fresh refresh token: %v", err)
+	}
+
+	if second.RefreshToken == first.RefreshToken || second.UserID != 1 {
+		t.Fatalf("rotation returned %+v", second)
+	}
+
+	_, err = service.ValidateToken(second.AccessToken)
+	if err != nil {
+		t.Errorf("rotated access token does not validate: %v", err)
+	}
+
+	third, err := service.RefreshToken(second.RefreshToken)
+	if err != nil {
+		t.Fatalf("rotating the new refresh token: %v", err)
+	}
+
+	if third.RefreshToken == second.RefreshToken {
+		t.Errorf("second rotation returned the same refresh token")
+	}
+}
+
+func TestRefreshTokenReuseRevokesEverySession(t *testing.T) {
+	service, _ := newTestService(t)
+
+	stolen, _ := service.GenerateTokenPair(1)
+	otherDevice, _ := service.GenerateTokenPair(1)
+	otherUser, _ := service.GenerateTokenPair(2)
+
+	current, err := service.RefreshToken(stolen.RefreshToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = service.RefreshToken(stolen.RefreshToken)
+	if err != ErrInvalidRefreshToken {
+		t.Fatalf("reusing a rotated token: err = %v, want ErrInvalidRefreshToken", err)
+	}
+
+	tests := []struct {
+		name         string
+		refreshToken string
+		want         error
+	}{
+		{"token issued by the rotation", current.RefreshToken, ErrInvalidRefreshToken},
+		{"other session of the same user", otherDevice.RefreshToken, ErrInvalidRefreshToken},
+		{"session of another user", otherUser.RefreshToken, nil},
+	}
+
+	for _, test := range tests {
+		_, err := service.RefreshToken(test.refreshToken)
+		if err != test.want {
+			t.Errorf("%s: err = %v, want %v", test.name, err, test.want)
+		}
+	}
+}
+
+func TestRefreshTokenAfterLogoutKeepsOtherSessions(t *testing.T) {
+	service, _ := newTestService(t)
+
+	loggedOut, _ := service.GenerateTokenPair(1)
+	otherDevice, _ := service.GenerateTokenPair(1)
+
+	token, err := service.ValidateToken(loggedOut.AccessToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = service.RevokeToken(token)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = service.ValidateToken(loggedOut.AccessToken)
+	if err != ErrTokenRevoked {
+		t.Errorf("access token after logout: err = %v, want ErrTokenRevoked", err)
+	}
+
+	// A stale tab sending the logged out refresh token again, twice.
+	for i := 0; i < 2; i++ {
+		_, err = service.RefreshToken(loggedOut.RefreshToken)
+		if err != ErrInvalidRefreshToken {
+			t.Errorf("refresh after logout: err = %v, want ErrInvalidRefreshToken", err)
+		}
+	}
+
+	_, err = service.RefreshToken(otherDevice.RefreshToken)
+	if err != nil {
+		t.Errorf("other session was signed out by a logged out token: %v", err)
+	}
+}
+
+func TestRefreshTokenRejectsUnknownAndExpiredTokens(t *testing.T) {
+	service, repository := newTestService(t)
+
+	expired, _ := service.GenerateTokenPair(1)
+	repository.tokens[0].ExpiresAt = time.Now().Add(-time.Minute)
+
+	tests := []struct {
+		name         string
+		refreshToken string
+		want         error
+	}{
+		{"unknown", "not-a-refresh-token", ErrInvalidRefreshToken},
+		{"empty", "", ErrInvalidRefreshToken},
+		{"expired", expired.RefreshToken, ErrRefreshTokenExpired},
+	}
+
+	for _, test := range tests {
+		_, err := service.RefreshToken(test.refreshToken)
+		if err != test.want {
+			t.Errorf("%s: err = %v, want %v", test.name, err, test.want)
+		}
+	}
+}
@@ -11,22 +11,64 @@ type Campaign struct {
 	Name             string
 	ShortDescription string
 	Description      string
-	Perks            string
 	BackerCount      int
 	GoalAmount       int
 	CurrentAmount    int
-	Slug             string
+	Slug             string `gorm:"uniqueIndex"`
+	Status           string `gorm:"default:published;index"`
+	Deadline         *time.Time
+	UnpublishedAt    *time.Time
 	CreatedAt        time.Time
 	UpdatedAt        time.Time
 	CampaignImages   []CampaignImage
+	Rewards          []Reward
 	User             user.User
 }
 
 type CampaignImage struct {
+	ID                int
+	CampaignID        int
+	FileName          string
+	CardFileName      string
+	ThumbnailFileName string
+	IsPrimary         int
+	Position          int
+	CreatedAt         time.Time
+	UpdatedAt         time.Time
+}
+
+// CampaignSlug is a slug a campaign used before it was renamed, kept so old
+// links still lead to the campaign.
+type CampaignSlug struct {
 	ID         int
-	CampaignID int
-	FileName   string
-	IsPrimary  int
+	CampaignID int    `gorm:"index"`
+	Slug       string `gorm:"uniqueIndex"`
 	CreatedAt  time.Time
-	UpdatedAt  time.Time
+}
+
+// Reward is a tier backers can pick when pledging. A nil Quantity means the
+// reward is unlimited.
+type Reward struct {
+	ID                int
+	CampaignID        int `gorm:"index"`
+	Title             string
+	Description       string
+	MinimumPledge     int
+	Quantity          *int
+	ClaimedCount      int
+	EstimatedDelivery *time.Time
+	CreatedAt         time.Time
+	UpdatedAt         time.Time
+}
+
+// IsAvailable reports whether the reward can still be claimed.
+func (r Reward) IsAvailable() bool {
+	return r.Quantity == nil || r.ClaimedCount < *r.Quantity
+}
+
+// SearchResult is a campaign matched by a search, in ranking order.
+type SearchResult struct {
+	Campaign  Campaign
+	Rank      float64
+	Highlight string
 }
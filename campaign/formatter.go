@@ -1,16 +1,18 @@
 package campaign
 
-import "strings"
+import "time"
 
 type CampaignFormatter struct {
-	ID               int    `json:"id"`
-	UserID           int    `json:"user_id"`
-	Name             string `json:"name"`
-	ShortDescription string `json:"short_description"`
-	ImageURL         string `json:"image_url"`
-	GoalAmount       int    `json:"goal_amount"`
-	CurrentAmount    int    `json:"current_amount"`
-	Slug             string `json:"slug"`
+	ID               int        `json:"id"`
+	UserID           int        `json:"user_id"`
+	Name             string     `json:"name"`
+	ShortDescription string     `json:"short_description"`
+	ImageURL         string     `json:"image_url"`
+	GoalAmount       int        `json:"goal_amount"`
+	CurrentAmount    int        `json:"current_amount"`
+	Slug             string     `json:"slug"`
+	Status           string     `json:"status"`
+	Deadline         *time.Time `json:"deadline"`
 }
 
 func FormatCampaign(campaign Campaign) CampaignFormatter {
@@ -23,6 +25,8 @@ func FormatCampaign(campaign Campaign) CampaignFormatter {
 	campaignFormatter.GoalAmount = campaign.GoalAmount
 	campaignFormatter.CurrentAmount = campaign.CurrentAmount
 	campaignFormatter.Slug = campaign.Slug
+	campaignFormatter.Status = campaign.Status
+	campaignFormatter.Deadline = campaign.Deadline
 	campaignFormatter.ImageURL = ""
 
 	if len(campaign.CampaignImages) > 0 {
@@ -43,6 +47,27 @@ func FormatCampaigns(campaigns []Campaign) []CampaignFormatter {
 	return campaignsFormatter
 }
 
+type CampaignSearchFormatter struct {
+	CampaignFormatter
+	Rank      float64 `json:"rank"`
+	Highlight string  `json:"highlight"`
+}
+
+func FormatSearchResults(results []SearchResult) []CampaignSearchFormatter {
+	searchFormatters := []CampaignSearchFormatter{}
+
+	for _, result := range results {
+		searchFormatter := CampaignSearchFormatter{}
+		searchFormatter.CampaignFormatter = FormatCampaign(result.Campaign)
+		searchFormatter.Rank = result.Rank
+		searchFormatter.Highlight = result.Highlight
+
+		searchFormatters = append(searchFormatters, searchFormatter)
+	}
+
+	return searchFormatters
+}
+
 type CampaignDetailFormatter struct {
 	ID               int                      `json:"id"`
 	Name             string                   `json:"name"`
@@ -54,7 +79,9 @@ type CampaignDetailFormatter struct {
 	BackerCount      int                      `json:"backer_count"`
 	UserID           int                      `json:"user_id"`
 	Slug             string                   `json:"slug"`
-	Perks            []string                 `json:"perks"`
+	Status           string                   `json:"status"`
+	Deadline         *time.Time               `json:"deadline"`
+	Rewards          []RewardFormatter        `json:"rewards"`
 	User             CampaignUserFormatter    `json:"user"`
 	Images           []CampaignImageFormatter `json:"images"`
 }
@@ -65,8 +92,61 @@ type CampaignUserFormatter struct {
 }
 
 type CampaignImageFormatter struct {
-	ImageURL  string `json:"image_url"`
-	IsPrimary bool   `json:"is_primary"`
+	ID        int                           `json:"id"`
+	ImageURL  string                        `json:"image_url"`
+	IsPrimary bool                          `json:"is_primary"`
+	Position  int                           `json:"position"`
+	Variants  CampaignImageVariantFormatter `json:"variants"`
+}
+
+type CampaignImageVariantFormatter struct {
+	Thumbnail string `json:"thumbnail"`
+	Card      string `json:"card"`
+	Full      string `json:"full"`
+}
+
+// FormatCampaignImageVariants falls back to the original file for images
+// uploaded before variants were generated.
+func FormatCampaignImageVariants(image CampaignImage) CampaignImageVariantFormatter {
+	formatter := CampaignImageVariantFormatter{}
+	formatter.Full = image.FileName
+	formatter.Card = image.FileName
+	formatter.Thumbnail = image.FileName
+
+	if image.CardFileName != "" {
+		formatter.Card = image.CardFileName
+	}
+
+	if image.ThumbnailFileName != "" {
+		formatter.Thumbnail = image.ThumbnailFileName
+	}
+
+	return formatter
+}
+
+func FormatCampaignImage(image CampaignImage) CampaignImageFormatter {
+	campaignImageFormatter := CampaignImageFormatter{}
+	campaignImageFormatter.ID = image.ID
+	campaignImageFormatter.ImageURL = image.FileName
+	isPrimary := false
+	if image.IsPrimary == 1 {
+		isPrimary = true
+	}
+	campaignImageFormatter.IsPrimary = isPrimary
+	campaignImageFormatter.Position = image.Position
+	campaignImageFormatter.Variants = FormatCampaignImageVariants(image)
+
+	return campaignImageFormatter
+}
+
+func FormatCampaignImages(images []CampaignImage) []CampaignImageFormatter {
+	imagesFormatter := []CampaignImageFormatter{}
+
+	for _, image := range images {
+		imagesFormatter = append(imagesFormatter, FormatCampaignImage(image))
+	}
+
+	return imagesFormatter
 }
 
 func FormatCampaignDetail(campaign Campaign) CampaignDetailFormatter {
@@ -79,20 +159,24 @@ func FormatCampaignDetail(campaign Campaign) CampaignDetailFormatter {
 	campaignDetailFormatter.CurrentAmount = campaign.CurrentAmount
 	campaignDetailFormatter.BackerCount = campaign.BackerCount
 	campaignDetailFormatter.Slug = campaign.Slug
+	campaignDetailFormatter.Status = campaign.Status
+	campaignDetailFormatter.Deadline = campaign.Deadline
 	campaignDetailFormatter.UserID = campaign.UserID
 	campaignDetailFormatter.ImageURL = ""
 
+	// Images come ordered by position, which need not put the primary first.
 	if len(campaign.CampaignImages) > 0 {
 		campaignDetailFormatter.ImageURL = campaign.CampaignImages[0].FileName
 
+		for _, image := range campaign.CampaignImages {
+			if image.IsPrimary == 1 {
+				campaignDetailFormatter.ImageURL = image.FileName
+				break
+			}
+		}
 	}
 
-	var perks []string
-	for _, perk := range strings.Split(campaign.Perks, ",") {
-		perks = append(perks, strings.TrimSpace(perk))
-	}
-
-	campaignDetailFormatter.Perks = perks
+	campaignDetailFormatter.Rewards = FormatRewards(campaign.Rewards)
 
 	user := campaign.User
 	campaignUserFormatter := CampaignUserFormatter{}
@@ -101,20 +185,50 @@ func FormatCampaignDetail(campaign Campaign) CampaignDetailFormatter {
 
 	campaignDetailFormatter.User = campaignUserFormatter
 
-	images := []CampaignImageFormatter{}
+	campaignDetailFormatter.Images = FormatCampaignImages(campaign.CampaignImages)
+	return campaignDetailFormatter
+}
 
-	for _, image := range campaign.CampaignImages {
-		campaignImageFormatter := CampaignImageFormatter{}
-		campaignImageFormatter.ImageURL = image.FileName
-		isPrimary := false
-		if image.IsPrimary == 1 {
-			isPrimary = true
+type RewardFormatter struct {
+	ID                int        `json:"id"`
+	Title             string     `json:"title"`
+	Description       string     `json:"description"`
+	MinimumPledge     int        `json:"minimum_pledge"`
+	Quantity          *int       `json:"quantity"`
+	ClaimedCount      int        `json:"claimed_count"`
+	Remaining         *int       `json:"remaining"`
+	EstimatedDelivery *time.Time `json:"estimated_delivery"`
+	IsAvailable       bool       `json:"is_available"`
+}
+
+func FormatReward(reward Reward) RewardFormatter {
+	rewardFormatter := RewardFormatter{}
+	rewardFormatter.ID = reward.ID
+	rewardFormatter.Title = reward.Title
+	rewardFormatter.Description = reward.Description
+	rewardFormatter.MinimumPledge = reward.MinimumPledge
+	rewardFormatter.Quantity = reward.Quantity
+	rewardFormatter.ClaimedCount = reward.ClaimedCount
+	rewardFormatter.EstimatedDelivery = reward.EstimatedDelivery
+	rewardFormatter.IsAvailable = reward.IsAvailable()
+
+	if reward.Quantity != nil {
+		remaining := *reward.Quantity - reward.ClaimedCount
+		if remaining < 0 {
+			remaining = 0
 		}
-		campaignImageFormatter.IsPrimary = isPrimary
+		rewardFormatter.Remaining = &remaining
+	}
 
-		images = append(images, campaignImageFormatter)
+	return rewardFormatter
+}
+
+func FormatRewards(rewards []Reward) []RewardFormatter {
+	rewardsFormatter := []RewardFormatter{}
+
+	for _, reward := range rewards {
+		rewardsFormatter = append(rewardsFormatter, FormatReward(reward))
 	}
 
-	campaignDetailFormatter.Images = images
-	return campaignDetailFormatter
+	return rewardsFormatter
 }
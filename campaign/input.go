@@ -1,17 +1,48 @@
 package campaign
 
-import "bwastartup/user"
+import (
+	"bwastartup/user"
+	"time"
+)
+
+type GetCampaignsInput struct {
+	UserID        int    `form:"user_id"`
+	Page          int    `form:"page" binding:"omitempty,min=1"`
+	Limit         int    `form:"limit" binding:"omitempty,min=1,max=100"`
+	Cursor        string `form:"cursor"`
+	Sort          string `form:"sort" binding:"omitempty,oneof=newest most_funded closest_to_goal most_backers"`
+	FundingStatus string `form:"funding_status" binding:"omitempty,oneof=funding funded"`
+	MinGoal       int    `form:"min_goal" binding:"omitempty,min=0"`
+	MaxGoal       int    `form:"max_goal" binding:"omitempty,min=0"`
+}
+
+type SearchCampaignsInput struct {
+	Query string `form:"q" binding:"required"`
+	Page  int    `form:"page" binding:"omitempty,min=1"`
+	Limit int    `form:"limit" binding:"omitempty,min=1,max=100"`
+}
 
 type GetCampaignDetailInput struct {
-	ID int `uri:"id" binding:"required"`
+	ID   int `uri:"id" binding:"required"`
+	User user.User
+}
+
+type GetCampaignBySlugInput struct {
+	Slug string `uri:"slug" binding:"required"`
+	User user.User
+}
+
+type ChangeCampaignStatusInput struct {
+	ID   int `uri:"id" binding:"required"`
+	User user.User
 }
 
 type CreateCampaignInput struct {
-	Name             string `json:"name" binding:"required"`
-	ShortDescription string `json:"short_description" binding:"required"`
-	Description      string `json:"description" binding:"required"`
-	GoalAmount       int    `json:"goal_amount" binding:"required"`
-	Perks            string `json:"perks" binding:"required"`
+	Name             string    `json:"name" binding:"required"`
+	ShortDescription string    `json:"short_description" binding:"required"`
+	Description      string    `json:"description" binding:"required"`
+	GoalAmount       int       `json:"goal_amount" binding:"required"`
+	Deadline         time.Time `json:"deadline" binding:"required"`
 	User             user.User
 }
 
@@ -20,3 +51,37 @@ type CreateCampaignImageInput struct {
 	IsPrimary  bool `form:"is_primary"`
 	User       user.User
 }
+
+type GetCampaignImageInput struct {
+	ID      int `uri:"id" binding:"required"`
+	ImageID int `uri:"image_id" binding:"required"`
+	User    user.User
+}
+
+type ReorderCampaignImagesInput struct {
+	ImageIDs []int `json:"image_ids" binding:"required,min=1"`
+	User     user.User
+}
+
+// CampaignImageFiles holds the stored location of every generated variant of
+// an uploaded campaign image.
+type CampaignImageFiles struct {
+	Full      string
+	Card      string
+	Thumbnail string
+}
+
+type GetRewardInput struct {
+	ID       int `uri:"id" binding:"required"`
+	RewardID int `uri:"reward_id" binding:"required"`
+	User     user.User
+}
+
+type RewardInput struct {
+	Title             string     `json:"title" binding:"required"`
+	Description       string     `json:"description"`
+	MinimumPledge     int        `json:"minimum_pledge" binding:"required,min=1"`
+	Quantity          *int       `json:"quantity" binding:"omitempty,min=1"`
+	EstimatedDelivery *time.Time `json:"estimated_delivery"`
+	User              user.User
+}
@@ -0,0 +1,74 @@
+package campaign
+
+import (
+	"errors"
+	"log"
+	"time"
+)
+
+const (
+	StatusDraft     = "draft"
+	StatusPublished = "published"
+	StatusFunded    = "funded"
+	StatusFailed    = "failed"
+	StatusClosed    = "closed"
+)
+
+var (
+	ErrInvalidTransition = errors.New("campaign cannot move to that status")
+	ErrDeadlineInPast    = errors.New("deadline must be in the future")
+	ErrDeadlineLocked    = errors.New("deadline can only be changed before the campaign is published")
+)
+
+// transitions lists the statuses each status can move to. Funded and failed
+// are only reached through the deadline passing, see ExpireCampaigns.
+var transitions = map[string][]string{
+	StatusDraft:     {StatusPublished},
+	StatusPublished: {StatusFunded, StatusFailed},
+	StatusFunded:    {StatusClosed},
+	StatusFailed:    {StatusClosed},
+}
+
+func canTransition(from string, to string) bool {
+	for _, status := range transitions[from] {
+		if status == to {
+			return true
+		}
+	}
+
+	return false
+}
+
+// IsListed reports whether the campaign shows up in public listings and
+// search results.
+func (c Campaign) IsListed() bool {
+	return c.UnpublishedAt == nil && c.Status != StatusDraft
+}
+
+// AcceptsPledges reports whether the campaign can be backed at now. A
+// campaign without a deadline never does, see BackfillDeadlines.
+func (c Campaign) AcceptsPledges(now time.Time) bool {
+	if c.Status != StatusPublished || c.UnpublishedAt != nil || c.Deadline == nil {
+		return false
+	}
+
+	return now.Before(*c.Deadline)
+}
+
+// RunScheduler ends every published campaign whose deadline has passed, once
+// per interval. It never returns.
+func RunScheduler(service Service, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		expired, err := service.ExpireCampaigns(time.Now())
+		if err != nil {
+			log.Println("expire campaigns:", err.Error())
+		} else if expired > 0 {
+			log.Printf("expired %d campaigns", expired)
+		}
+
+		<-ticker.C
+	}
+}
@@ -0,0 +1,68 @@
+package campaign
+
+import (
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// MigratePerksToRewards turns the comma separated perks column campaigns
+// used to have into one reward per perk, then drops the column. It does
+// nothing once the column is gone.
+func MigratePerksToRewards(db *gorm.DB) error {
+	if !db.Migrator().HasColumn(&Campaign{}, "perks") {
+		return nil
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		var rows []struct {
+			ID    int
+			Perks string
+		}
+
+		err := tx.Table("campaigns").Select("id, perks").Where("perks IS NOT NULL AND perks <> ''").Scan(&rows).Error
+		if err != nil {
+			return err
+		}
+
+		for _, row := range rows {
+			for _, perk := range strings.Split(row.Perks, ",") {
+				title := strings.TrimSpace(perk)
+				if title == "" {
+					continue
+				}
+
+				err := tx.Create(&Reward{CampaignID: row.ID, Title: title}).Error
+				if err != nil {
+					return err
+				}
+			}
+		}
+
+		return tx.Migrator().DropColumn(&Campaign{}, "perks")
+	})
+}
+
+// DeduplicateSlugs appends the campaign id to every slug but the oldest one
+// that is used more than once, so the unique index on slugs can be created.
+// It has to run before AutoMigrate.
+func DeduplicateSlugs(db *gorm.DB) error {
+	if !db.Migrator().HasTable(&Campaign{}) {
+		return nil
+	}
+
+	return db.Exec(`UPDATE campaigns SET slug = slug || '-' || id
+		WHERE id IN (
+			SELECT id FROM (
+				SELECT id, ROW_NUMBER() OVER (PARTITION BY slug ORDER BY id) AS position FROM campaigns
+			) AS numbered WHERE position > 1
+		)`).Error
+}
+
+// BackfillDeadlines gives campaigns created before deadlines existed one,
+// grace from now, so they still get funded or failed and can be closed. It
+// has to run after AutoMigrate added the column.
+func BackfillDeadlines(db *gorm.DB, now time.Time, grace time.Duration) error {
+	return db.Model(&Campaign{}).Where("deadline IS NULL").Update("deadline", now.Add(grace)).Error
+}
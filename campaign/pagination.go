@@ -0,0 +1,114 @@
+package campaign
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math"
+	"time"
+)
+
+const (
+	SortNewest        = "newest"
+	SortMostFunded    = "most_funded"
+	SortClosestToGoal = "closest_to_goal"
+	SortMostBackers   = "most_backers"
+
+	DefaultLimit = 12
+)
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// sortExpressions are all sorted descending, with the id as tie breaker so
+// that a cursor always points at exactly one row. Closest to goal is the
+// negated distance of the funded ratio from 1, so over-funded campaigns do
+// not come before ones just short of their goal.
+var sortExpressions = map[string]string{
+	SortNewest:        "campaigns.created_at",
+	SortMostFunded:    "campaigns.current_amount",
+	SortClosestToGoal: "-ABS(1 - CAST(campaigns.current_amount AS DOUBLE PRECISION) / GREATEST(campaigns.goal_amount, 1))",
+	SortMostBackers:   "campaigns.backer_count",
+}
+
+type CampaignQuery struct {
+	UserID        int
+	FundingStatus string
+	MinGoal       int
+	MaxGoal       int
+	Sort          string
+	Limit         int
+	Offset        int
+	Cursor        *Cursor
+}
+
+// Cursor is the sort value and id of the last campaign of a page. Clients
+// get it base64 encoded and send it back unchanged.
+type Cursor struct {
+	Value interface{}
+	ID    int
+}
+
+type encodedCursor struct {
+	Value json.RawMessage `json:"v"`
+	ID    int             `json:"id"`
+}
+
+func sortValue(campaign Campaign, sort string) interface{} {
+	switch sort {
+	case SortMostFunded:
+		return campaign.CurrentAmount
+	case SortClosestToGoal:
+		goalAmount := campaign.GoalAmount
+		if goalAmount < 1 {
+			goalAmount = 1
+		}
+		return -math.Abs(1 - float64(campaign.CurrentAmount)/float64(goalAmount))
+	case SortMostBackers:
+		return campaign.BackerCount
+	default:
+		return campaign.CreatedAt
+	}
+}
+
+func EncodeCursor(campaign Campaign, sort string) string {
+	value, _ := json.Marshal(sortValue(campaign, sort))
+	data, _ := json.Marshal(encodedCursor{Value: value, ID: campaign.ID})
+
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func DecodeCursor(cursor string, sort string) (*Cursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	var encoded encodedCursor
+	err = json.Unmarshal(data, &encoded)
+	if err != nil || encoded.ID == 0 {
+		return nil, ErrInvalidCursor
+	}
+
+	decoded := &Cursor{ID: encoded.ID}
+
+	switch sort {
+	case SortNewest:
+		var value time.Time
+		err = json.Unmarshal(encoded.Value, &value)
+		decoded.Value = value
+	case SortClosestToGoal:
+		var value float64
+		err = json.Unmarshal(encoded.Value, &value)
+		decoded.Value = value
+	default:
+		var value int
+		err = json.Unmarshal(encoded.Value, &value)
+		decoded.Value = value
+	}
+
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	return decoded, nil
+}
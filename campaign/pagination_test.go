@@ -0,0 +1,95 @@
+package campaign
+
+import (
+	"encoding/base64"
+	"testing"
+	"time"
+)
+
+func TestCursorRoundTrip(t *testing.T) {
+	createdAt := time.Date(2021, 3, 4, 5, 6, 7, 890, time.UTC)
+	campaign := Campaign{ID: 42, CreatedAt: createdAt, CurrentAmount: 750, GoalAmount: 1000, BackerCount: 9}
+
+	tests := []struct {
+		sort string
+		want interface{}
+	}{
+		{SortNewest, createdAt},
+		{SortMostFunded, 750},
+		{SortClosestToGoal, -0.25},
+		{SortMostBackers, 9},
+	}
+
+	for _, test := range tests {
+		t.Run(test.sort, func(t *testing.T) {
+			cursor, err := DecodeCursor(EncodeCursor(campaign, test.sort), test.sort)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if cursor.ID != campaign.ID {
+				t.Errorf("ID = %d, want %d", cursor.ID, campaign.ID)
+			}
+
+			if value, ok := cursor.Value.(time.Time); ok {
+				if !value.Equal(test.want.(time.Time)) {
+					t.Errorf("Value = %v, want %v", value, test.want)
+				}
+				return
+			}
+
+			if cursor.Value != test.want {
+				t.Errorf("Value = %v (%T), want %v (%T)", cursor.Value, cursor.Value, test.want, test.want)
+			}
+		})
+	}
+}
+
+func TestDecodeCursorRejectsInvalidCursors(t *testing.T) {
+	encode := func(data string) string {
+		return base64.RawURLEncoding.EncodeToString([]byte(data))
+	}
+
+	tests := []struct {
+		name   string
+		cursor string
+		sort   string
+	}{
+		{"not base64", "%%%", SortNewest},
+		{"not json", encode("cursor"), SortNewest},
+		{"missing id", encode(`{"v":5}`), SortMostFunded},
+		{"value of another sort", encode(`{"v":"2021-03-04T05:06:07Z","id":1}`), SortMostFunded},
+		{"number for a time", encode(`{"v":5,"id":1}`), SortNewest},
+		{"string for a ratio", encode(`{"v":"close","id":1}`), SortClosestToGoal},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := DecodeCursor(test.cursor, test.sort)
+			if err != ErrInvalidCursor {
+				t.Errorf("err = %v, want ErrInvalidCursor", err)
+			}
+		})
+	}
+}
+
+func TestSortValueClosestToGoal(t *testing.T) {
+	// Listed in the order closest to goal should return them.
+	campaigns := []Campaign{
+		{ID: 1, CurrentAmount: 1000, GoalAmount: 1000},
+		{ID: 2, CurrentAmount: 950, GoalAmount: 1000},
+		{ID: 3, CurrentAmount: 1100, GoalAmount: 1000},
+		{ID: 4, CurrentAmount: 500, GoalAmount: 1000},
+		{ID: 5, CurrentAmount: 0, GoalAmount: 0},
+		{ID: 6, CurrentAmount: 3000, GoalAmount: 1000},
+	}
+
+	for i := 1; i < len(campaigns); i++ {
+		previous := sortValue(campaigns[i-1], SortClosestToGoal).(float64)
+		current := sortValue(campaigns[i], SortClosestToGoal).(float64)
+
+		if previous < current {
+			t.Errorf("campaign %d (%v) sorts after campaign %d (%v)", campaigns[i-1].ID, previous, campaigns[i].ID, current)
+		}
+	}
+}
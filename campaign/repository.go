@@ -1,15 +1,43 @@
 package campaign
 
-import "gorm.io/gorm"
+import (
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
 
 type Repository interface {
 	FindAll() ([]Campaign, error)
+	FindByQuery(query CampaignQuery) ([]Campaign, error)
+	CountByQuery(query CampaignQuery) (int64, error)
 	FIndByUserID(userID int) ([]Campaign, error)
 	FindByID(ID int) (Campaign, error)
+	FindByIDs(IDs []int) ([]Campaign, error)
+	FindBySlug(slug string) (Campaign, error)
+	FindSlugHistory(slug string) (CampaignSlug, error)
+	IsSlugTaken(slug string, campaignID int) (bool, error)
 	Save(campaign Campaign) (Campaign, error)
 	Update(campaign Campaign) (Campaign, error)
+	UpdateWithSlugHistory(campaign Campaign, oldSlug string) (Campaign, error)
 	CreateImage(campaignImage CampaignImage) (CampaignImage, error)
 	MarkAllImagesAsNonPrimary(campaignID int) (bool, error)
+	FindImageByID(ID int) (CampaignImage, error)
+	FindImagesByCampaignID(campaignID int) ([]CampaignImage, error)
+	NextImagePosition(campaignID int) (int, error)
+	DeleteImage(campaignImage CampaignImage) error
+	SetPrimaryImage(campaignID int, imageID int) error
+	UpdateImagePositions(campaignID int, imageIDs []int) error
+	FindExpired(now time.Time) ([]Campaign, error)
+	UpdateStatus(ID int, from string, to string) (bool, error)
+	IncrementFunding(ID int, backers int, amount int) error
+	FindRewardsByCampaignID(campaignID int) ([]Reward, error)
+	FindRewardByID(ID int) (Reward, error)
+	CreateReward(reward Reward) (Reward, error)
+	UpdateReward(reward Reward) (Reward, error)
+	DeleteReward(reward Reward) error
+	ReserveReward(ID int) (bool, error)
+	ReleaseReward(ID int) (bool, error)
 }
 
 type repository struct {
@@ -23,17 +51,69 @@ func NewRepository(db *gorm.DB) *repository {
 func (r *repository) FindAll() ([]Campaign, error) {
 	var campaigns []Campaign
 
-	err := r.db.Preload("CampaignImages", "campaign_images.is_primary = 1").Find(&campaigns).Error
+	err := r.db.Where("unpublished_at IS NULL AND status <> ?", StatusDraft).Preload("CampaignImages", "campaign_images.is_primary = 1").Preload("Rewards").Find(&campaigns).Error
 	if err != nil {
 		return campaigns, err
 	}
 	return campaigns, nil
 }
 
+func (r *repository) FindByQuery(query CampaignQuery) ([]Campaign, error) {
+	var campaigns []Campaign
+
+	sortExpression := sortExpressions[query.Sort]
+
+	db := r.filter(query)
+	if query.Cursor != nil {
+		db = db.Where("("+sortExpression+", campaigns.id) < (?, ?)", query.Cursor.Value, query.Cursor.ID)
+	}
+
+	err := db.Order(sortExpression+" DESC").Order("campaigns.id DESC").Limit(query.Limit).Offset(query.Offset).Preload("CampaignImages", "campaign_images.is_primary = 1").Find(&campaigns).Error
+	if err != nil {
+		return campaigns, err
+	}
+	return campaigns, nil
+}
+
+func (r *repository) CountByQuery(query CampaignQuery) (int64, error) {
+	var count int64
+
+	err := r.filter(query).Model(&Campaign{}).Count(&count).Error
+	if err != nil {
+		return count, err
+	}
+	return count, nil
+}
+
+func (r *repository) filter(query CampaignQuery) *gorm.DB {
+	db := r.db.Where("campaigns.unpublished_at IS NULL AND campaigns.status <> ?", StatusDraft)
+
+	if query.UserID != 0 {
+		db = db.Where("campaigns.user_id = ?", query.UserID)
+	}
+
+	switch query.FundingStatus {
+	case "funded":
+		db = db.Where("campaigns.current_amount >= campaigns.goal_amount")
+	case "funding":
+		db = db.Where("campaigns.current_amount < campaigns.goal_amount")
+	}
+
+	if query.MinGoal > 0 {
+		db = db.Where("campaigns.goal_amount >= ?", query.MinGoal)
+	}
+
+	if query.MaxGoal > 0 {
+		db = db.Where("campaigns.goal_amount <= ?", query.MaxGoal)
+	}
+
+	return db
+}
+
 func (r *repository) FIndByUserID(userID int) ([]Campaign, error) {
 	var campaigns []Campaign
 
-	err := r.db.Where("user_id = ?", userID).Preload("CampaignImages", "campaign_images.is_primary = 1").Find(&campaigns).Error
+	err := r.db.Where("user_id = ? AND unpublished_at IS NULL AND status <> ?", userID, StatusDraft).Preload("CampaignImages", "campaign_images.is_primary = 1").Find(&campaigns).Error
 	if err != nil {
 		return campaigns, err
 	}
@@ -43,7 +123,18 @@ func (r *repository) FIndByUserID(userID int) ([]Campaign, error) {
 func (r *repository) FindByID(ID int) (Campaign, error) {
 	var campaign Campaign
 
-	err := r.db.Where("id = ?", ID).Preload("CampaignImages").Preload("User").Find(&campaign).Error
+	err := r.db.Where("id = ?", ID).Preload("CampaignImages", orderImages).Preload("Rewards", orderRewards).Preload("User").Find(&campaign).Error
+	if err != nil {
+		return campaign, err
+	}
+
+	return campaign, nil
+}
+
+func (r *repository) FindBySlug(slug string) (Campaign, error) {
+	var campaign Campaign
+
+	err := r.db.Where("slug = ?", slug).Preload("CampaignImages", orderImages).Preload("Rewards", orderRewards).Preload("User").Find(&campaign).Error
 	if err != nil {
 		return campaign, err
 	}
@@ -51,6 +142,50 @@ func (r *repository) FindByID(ID int) (Campaign, error) {
 	return campaign, nil
 }
 
+func (r *repository) FindSlugHistory(slug string) (CampaignSlug, error) {
+	var campaignSlug CampaignSlug
+
+	err := r.db.Where("slug = ?", slug).Find(&campaignSlug).Error
+	if err != nil {
+		return campaignSlug, err
+	}
+
+	return campaignSlug, nil
+}
+
+// IsSlugTaken reports whether another campaign uses slug, now or in the
+// past. Old slugs stay reserved so their redirects keep working.
+func (r *repository) IsSlugTaken(slug string, campaignID int) (bool, error) {
+	var count int64
+
+	err := r.db.Model(&Campaign{}).Where("slug = ? AND id <> ?", slug, campaignID).Count(&count).Error
+	if err != nil {
+		return false, err
+	}
+
+	if count > 0 {
+		return true, nil
+	}
+
+	err = r.db.Model(&CampaignSlug{}).Where("slug = ? AND campaign_id <> ?", slug, campaignID).Count(&count).Error
+	if err != nil {
+		return false, err
+	}
+
+	return count > 0, nil
+}
+
+func (r *repository) FindByIDs(IDs []int) ([]Campaign, error) {
+	var campaigns []Campaign
+
+	err := r.db.Where("id IN ?", IDs).Preload("CampaignImages", "campaign_images.is_primary = 1").Find(&campaigns).Error
+	if err != nil {
+		return campaigns, err
+	}
+
+	return campaigns, nil
+}
+
 func (r *repository) Save(campaign Campaign) (Campaign, error) {
 	err := r.db.Create(&campaign).Error
 	if err != nil {
@@ -60,8 +195,39 @@ func (r *repository) Save(campaign Campaign) (Campaign, error) {
 	return campaign, nil
 }
 
+// Update never writes the status, which only changes through UpdateStatus,
+// the funding counters, which only change through IncrementFunding, nor the
+// preloaded images, rewards and user.
 func (r *repository) Update(campaign Campaign) (Campaign, error) {
-	err := r.db.Save(&campaign).Error
+	err := r.db.Omit("status", "backer_count", "current_amount", clause.Associations).Save(&campaign).Error
+	if err != nil {
+		return campaign, err
+	}
+
+	return campaign, nil
+}
+
+// UpdateWithSlugHistory updates a renamed campaign and keeps its old slug
+// in the history in one transaction, so the old links never stop resolving.
+func (r *repository) UpdateWithSlugHistory(campaign Campaign, oldSlug string) (Campaign, error) {
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Omit("status", "backer_count", "current_amount", clause.Associations).Save(&campaign).Error
+		if err != nil {
+			return err
+		}
+
+		if campaign.Slug == oldSlug {
+			return nil
+		}
+
+		// The campaign may be getting one of its own old slugs back.
+		err = tx.Where("campaign_id = ? AND slug = ?", campaign.ID, campaign.Slug).Delete(&CampaignSlug{}).Error
+		if err != nil {
+			return err
+		}
+
+		return tx.Create(&CampaignSlug{CampaignID: campaign.ID, Slug: oldSlug}).Error
+	})
 	if err != nil {
 		return campaign, err
 	}
@@ -79,11 +245,195 @@ func (r *repository) CreateImage(campaignImage CampaignImage) (CampaignImage, er
 }
 
 func (r *repository) MarkAllImagesAsNonPrimary(campaignID int) (bool, error) {
-	err := r.db.Model(&CampaignImage{}).Where("campaign_id = ?", campaignID).Update("is_primary", false).Error
+	err := r.db.Model(&CampaignImage{}).Where("campaign_id = ?", campaignID).Update("is_primary", 0).Error
 
 	if err != nil {
-		return false, nil
+		return false, err
 	}
 
 	return true, nil
 }
+
+func orderImages(db *gorm.DB) *gorm.DB {
+	return db.Order("campaign_images.position ASC").Order("campaign_images.id ASC")
+}
+
+func (r *repository) FindImageByID(ID int) (CampaignImage, error) {
+	var campaignImage CampaignImage
+
+	err := r.db.Where("id = ?", ID).Find(&campaignImage).Error
+	if err != nil {
+		return campaignImage, err
+	}
+
+	return campaignImage, nil
+}
+
+func (r *repository) FindImagesByCampaignID(campaignID int) ([]CampaignImage, error) {
+	var campaignImages []CampaignImage
+
+	err := orderImages(r.db.Where("campaign_id = ?", campaignID)).Find(&campaignImages).Error
+	if err != nil {
+		return campaignImages, err
+	}
+
+	return campaignImages, nil
+}
+
+func (r *repository) NextImagePosition(campaignID int) (int, error) {
+	var position int
+
+	err := r.db.Model(&CampaignImage{}).Select("COALESCE(MAX(position), 0) + 1").Where("campaign_id = ?", campaignID).Scan(&position).Error
+	if err != nil {
+		return position, err
+	}
+
+	return position, nil
+}
+
+func (r *repository) DeleteImage(campaignImage CampaignImage) error {
+	err := r.db.Delete(&campaignImage).Error
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// SetPrimaryImage makes imageID the only primary image of the campaign.
+func (r *repository) SetPrimaryImage(campaignID int, imageID int) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&CampaignImage{}).Where("campaign_id = ?", campaignID).Update("is_primary", 0).Error
+		if err != nil {
+			return err
+		}
+
+		return tx.Model(&CampaignImage{}).Where("id = ? AND campaign_id = ?", imageID, campaignID).Update("is_primary", 1).Error
+	})
+}
+
+// UpdateImagePositions numbers the images in the order of imageIDs.
+func (r *repository) UpdateImagePositions(campaignID int, imageIDs []int) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		for i, imageID := range imageIDs {
+			err := tx.Model(&CampaignImage{}).Where("id = ? AND campaign_id = ?", imageID, campaignID).Update("position", i+1).Error
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+func (r *repository) FindExpired(now time.Time) ([]Campaign, error) {
+	var campaigns []Campaign
+
+	err := r.db.Where("status = ? AND deadline <= ?", StatusPublished, now).Preload("Rewards").Find(&campaigns).Error
+	if err != nil {
+		return campaigns, err
+	}
+
+	return campaigns, nil
+}
+
+// UpdateStatus only changes the status if it is still from, so two
+// concurrent transitions cannot both succeed.
+func (r *repository) UpdateStatus(ID int, from string, to string) (bool, error) {
+	result := r.db.Model(&Campaign{}).Where("id = ? AND status = ?", ID, from).Update("status", to)
+	if result.Error != nil {
+		return false, result.Error
+	}
+
+	return result.RowsAffected == 1, nil
+}
+
+func orderRewards(db *gorm.DB) *gorm.DB {
+	return db.Order("rewards.minimum_pledge ASC").Order("rewards.id ASC")
+}
+
+func (r *repository) FindRewardsByCampaignID(campaignID int) ([]Reward, error) {
+	var rewards []Reward
+
+	err := orderRewards(r.db.Where("campaign_id = ?", campaignID)).Find(&rewards).Error
+	if err != nil {
+		return rewards, err
+	}
+
+	return rewards, nil
+}
+
+func (r *repository) FindRewardByID(ID int) (Reward, error) {
+	var reward Reward
+
+	err := r.db.Where("id = ?", ID).Find(&reward).Error
+	if err != nil {
+		return reward, err
+	}
+
+	return reward, nil
+}
+
+func (r *repository) CreateReward(reward Reward) (Reward, error) {
+	err := r.db.Create(&reward).Error
+	if err != nil {
+		return reward, err
+	}
+
+	return reward, nil
+}
+
+// UpdateReward never writes the claimed count, which is owned by pledges.
+func (r *repository) UpdateReward(reward Reward) (Reward, error) {
+	err := r.db.Omit("claimed_count").Save(&reward).Error
+	if err != nil {
+		return reward, err
+	}
+
+	return reward, nil
+}
+
+func (r *repository) DeleteReward(reward Reward) error {
+	err := r.db.Delete(&reward).Error
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// IncrementFunding adds to the backer count and current amount in the
+// database, so concurrent payments never overwrite each other. Pass negative
+// values to take a refunded payment off again.
+func (r *repository) IncrementFunding(ID int, backers int, amount int) error {
+	err := r.db.Model(&Campaign{}).Where("id = ?", ID).Updates(map[string]interface{}{
+		"backer_count":   gorm.Expr("backer_count + ?", backers),
+		"current_amount": gorm.Expr("current_amount + ?", amount),
+	}).Error
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// ReserveReward claims one unit of a reward in a single statement, so
+// concurrent pledges can never claim more than the quantity. It reports
+// false when the reward is sold out.
+func (r *repository) ReserveReward(ID int) (bool, error) {
+	result := r.db.Model(&Reward{}).Where("id = ? AND (quantity IS NULL OR claimed_count < quantity)", ID).Update("claimed_count", gorm.Expr("claimed_count + 1"))
+	if result.Error != nil {
+		return false, result.Error
+	}
+
+	return result.RowsAffected == 1, nil
+}
+
+func (r *repository) ReleaseReward(ID int) (bool, error) {
+	result := r.db.Model(&Reward{}).Where("id = ? AND claimed_count > 0", ID).Update("claimed_count", gorm.Expr("claimed_count - 1"))
+	if result.Error != nil {
+		return false, result.Error
+	}
+
+	return result.RowsAffected == 1, nil
+}
@@ -0,0 +1,349 @@
+package campaign
+
+import (
+	"html"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+
+	"gorm.io/gorm"
+)
+
+// SearchIndex keeps a searchable copy of the campaign text, rewards
+// included. Index is called every time a campaign or one of its rewards is
+// created or changed, and must drop campaigns that are no longer public.
+type SearchIndex interface {
+	Index(campaign Campaign) error
+	Search(query string, limit int, offset int) ([]SearchHit, int64, error)
+}
+
+// SearchHit is one matching campaign. Highlight is a short, HTML-escaped
+// excerpt with the matched words wrapped in <mark> tags.
+type SearchHit struct {
+	CampaignID int
+	Rank       float64
+	Highlight  string
+}
+
+const (
+	highlightStart = "<mark>"
+	highlightStop  = "</mark>"
+)
+
+// ts_headline does not escape the campaign text, so Postgres marks matches
+// with private-use characters that survive html.EscapeString and are only
+// swapped for the tags afterwards.
+const (
+	headlineStart = "\uE000"
+	headlineStop  = "\uE001"
+)
+
+// searchDocument builds the weighted tsvector of a campaign row. The name
+// weighs the most, the long description the least.
+const searchDocument = `setweight(to_tsvector('simple', coalesce(campaigns.name, '')), 'A') ||
+	setweight(to_tsvector('simple', coalesce(campaigns.short_description, '')), 'B') ||
+	setweight(to_tsvector('simple', coalesce((SELECT string_agg(rewards.title || ' ' || rewards.description, ' ')
+		FROM rewards WHERE rewards.campaign_id = campaigns.id), '')), 'C') ||
+	setweight(to_tsvector('simple', coalesce(campaigns.description, '')), 'D')`
+
+type postgresSearchIndex struct {
+	db *gorm.DB
+}
+
+func NewPostgresSearchIndex(db *gorm.DB) *postgresSearchIndex {
+	return &postgresSearchIndex{db}
+}
+
+// Migrate adds the search_vector column and its GIN index, and fills it for
+// campaigns created before search existed.
+func (s *postgresSearchIndex) Migrate() error {
+	statements := []string{
+		"ALTER TABLE campaigns ADD COLUMN IF NOT EXISTS search_vector tsvector",
+		"CREATE INDEX IF NOT EXISTS idx_campaigns_search_vector ON campaigns USING GIN (search_vector)",
+		"UPDATE campaigns SET search_vector = " + searchDocument + " WHERE search_vector IS NULL",
+	}
+
+	for _, statement := range statements {
+		err := s.db.Exec(statement).Error
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *postgresSearchIndex) Index(campaign Campaign) error {
+	err := s.db.Exec("UPDATE campaigns SET search_vector = "+searchDocument+" WHERE campaigns.id = ?", campaign.ID).Error
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (s *postgresSearchIndex) Search(query string, limit int, offset int) ([]SearchHit, int64, error) {
+	var hits []SearchHit
+	var total int64
+
+	err := s.db.Raw(`SELECT count(*) FROM campaigns
+		WHERE campaigns.search_vector @@ websearch_to_tsquery('simple', ?)
+			AND campaigns.unpublished_at IS NULL AND campaigns.status <> ?`, query, StatusDraft).Scan(&total).Error
+	if err != nil {
+		return hits, total, err
+	}
+
+	err = s.db.Raw(`SELECT campaigns.id AS campaign_id,
+			ts_rank(campaigns.search_vector, query) AS rank,
+			ts_headline('simple', campaigns.short_description || ' ' || campaigns.description, query,
+				'StartSel=`+headlineStart+`, StopSel=`+headlineStop+`, MinWords=10, MaxWords=30') AS highlight
+		FROM campaigns, websearch_to_tsquery('simple', ?) query
+		WHERE campaigns.search_vector @@ query AND campaigns.unpublished_at IS NULL AND campaigns.status <> ?
+		ORDER BY rank DESC, campaigns.id DESC
+		LIMIT ? OFFSET ?`, query, StatusDraft, limit, offset).Scan(&hits).Error
+	if err != nil {
+		return hits, total, err
+	}
+
+	for i := range hits {
+		hits[i].Highlight = escapeHeadline(hits[i].Highlight)
+	}
+
+	return hits, total, nil
+}
+
+type memoryDocument struct {
+	name             string
+	shortDescription string
+	description      string
+}
+
+// memorySearchIndex is an inverted index held in process memory, for tests
+// and for databases without full-text search. Every query word has to match,
+// and matches are weighted like the Postgres index.
+type memorySearchIndex struct {
+	mu        sync.RWMutex
+	documents map[int]memoryDocument
+	postings  map[string]map[int]float64
+}
+
+func NewMemorySearchIndex() *memorySearchIndex {
+	return &memorySearchIndex{
+		documents: map[int]memoryDocument{},
+		postings:  map[string]map[int]float64{},
+	}
+}
+
+func (s *memorySearchIndex) Index(campaign Campaign) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.remove(campaign.ID)
+
+	if !campaign.IsListed() {
+		return nil
+	}
+
+	s.documents[campaign.ID] = memoryDocument{
+		name:             campaign.Name,
+		shortDescription: campaign.ShortDescription,
+		description:      campaign.Description,
+	}
+
+	type field struct {
+		text   string
+		weight float64
+	}
+
+	fields := []field{
+		{campaign.Name, 1.0},
+		{campaign.ShortDescription, 0.4},
+		{campaign.Description, 0.1},
+	}
+
+	for _, reward := range campaign.Rewards {
+		fields = append(fields, field{reward.Title + " " + reward.Description, 0.2})
+	}
+
+	for _, field := range fields {
+		for _, term := range tokenize(field.text) {
+			if s.postings[term] == nil {
+				s.postings[term] = map[int]float64{}
+			}
+
+			s.postings[term][campaign.ID] += field.weight
+		}
+	}
+
+	return nil
+}
+
+func (s *memorySearchIndex) remove(campaignID int) {
+	if _, ok := s.documents[campaignID]; !ok {
+		return
+	}
+
+	delete(s.documents, campaignID)
+
+	for term, posting := range s.postings {
+		delete(posting, campaignID)
+		if len(posting) == 0 {
+			delete(s.postings, term)
+		}
+	}
+}
+
+func escapeHeadline(headline string) string {
+	headline = html.EscapeString(headline)
+	headline = strings.ReplaceAll(headline, headlineStart, highlightStart)
+	headline = strings.ReplaceAll(headline, headlineStop, highlightStop)
+
+	return headline
+}
+
+func (s *memorySearchIndex) Search(query string, limit int, offset int) ([]SearchHit, int64, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	terms := uniqueTerms(tokenize(query))
+	if len(terms) == 0 {
+		return []SearchHit{}, 0, nil
+	}
+
+	scores := map[int]float64{}
+	for campaignID, weight := range s.postings[terms[0]] {
+		scores[campaignID] = weight
+	}
+
+	for _, term := range terms[1:] {
+		posting := s.postings[term]
+		for campaignID := range scores {
+			weight, ok := posting[campaignID]
+			if !ok {
+				delete(scores, campaignID)
+				continue
+			}
+
+			scores[campaignID] += weight
+		}
+	}
+
+	hits := []SearchHit{}
+	for campaignID, score := range scores {
+		hits = append(hits, SearchHit{CampaignID: campaignID, Rank: score})
+	}
+
+	sort.Slice(hits, func(i, j int) bool {
+		if hits[i].Rank != hits[j].Rank {
+			return hits[i].Rank > hits[j].Rank
+		}
+		return hits[i].CampaignID > hits[j].CampaignID
+	})
+
+	total := int64(len(hits))
+
+	if offset >= len(hits) {
+		return []SearchHit{}, total, nil
+	}
+
+	hits = hits[offset:]
+	if len(hits) > limit {
+		hits = hits[:limit]
+	}
+
+	for i := range hits {
+		document := s.documents[hits[i].CampaignID]
+		hits[i].Highlight = highlight(document.shortDescription+" "+document.description, terms)
+	}
+
+	return hits, total, nil
+}
+
+// BuildSearchIndex indexes every public campaign, for indexes that do not
+// survive a restart.
+func BuildSearchIndex(repository Repository, index SearchIndex) error {
+	campaigns, err := repository.FindAll()
+	if err != nil {
+		return err
+	}
+
+	for _, campaign := range campaigns {
+		err := index.Index(campaign)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func tokenize(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+}
+
+func uniqueTerms(terms []string) []string {
+	seen := map[string]bool{}
+
+	var unique []string
+	for _, term := range terms {
+		if !seen[term] {
+			seen[term] = true
+			unique = append(unique, term)
+		}
+	}
+
+	return unique
+}
+
+// highlight returns up to 30 HTML-escaped words of text starting a few words
+// before the first match, with every matching word marked.
+func highlight(text string, terms []string) string {
+	matches := map[string]bool{}
+	for _, term := range terms {
+		matches[term] = true
+	}
+
+	words := strings.Fields(text)
+
+	first := -1
+	for i, word := range words {
+		if isMatch(word, matches) {
+			first = i
+			break
+		}
+	}
+
+	start := 0
+	if first > 5 {
+		start = first - 5
+	}
+
+	end := start + 30
+	if end > len(words) {
+		end = len(words)
+	}
+
+	excerpt := make([]string, 0, end-start)
+	for _, word := range words[start:end] {
+		escaped := html.EscapeString(word)
+		if isMatch(word, matches) {
+			escaped = highlightStart + escaped + highlightStop
+		}
+		excerpt = append(excerpt, escaped)
+	}
+
+	return strings.Join(excerpt, " ")
+}
+
+func isMatch(word string, matches map[string]bool) bool {
+	for _, term := range tokenize(word) {
+		if matches[term] {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,156 @@
+package campaign
+
+import (
+	"testing"
+	"time"
+)
+
+func newTestSearchIndex(t *testing.T) *memorySearchIndex {
+	now := time.Now()
+	campaigns := []Campaign{
+		{ID: 1, Status: StatusPublished, Name: "Solar Lamp", ShortDescription: "Light for every village", Description: "A cheap lamp."},
+		{ID: 2, Status: StatusPublished, Name: "Village Library", ShortDescription: "Books for kids", Description: "Bring solar power to the library."},
+		{ID: 3, Status: StatusPublished, Name: "Board Game", ShortDescription: "A game night", Description: "Play it.", Rewards: []Reward{{Title: "Solar edition", Description: "Glows in the dark"}}},
+		{ID: 4, Status: StatusDraft, Name: "Solar Draft", ShortDescription: "Not public yet"},
+		{ID: 5, Status: StatusPublished, UnpublishedAt: &now, Name: "Solar Unpublished", ShortDescription: "Taken down"},
+	}
+
+	index := NewMemorySearchIndex()
+	for _, campaign := range campaigns {
+		err := index.Index(campaign)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return index
+}
+
+func TestMemorySearchIndexSearch(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		want  []int
+	}{
+		{"name ranks above reward and description", "solar", []int{1, 3, 2}},
+		{"every word has to match", "solar village", []int{1, 2}},
+		{"case and punctuation are ignored", "VILLAGE!", []int{2, 1}},
+		{"no match", "submarine", []int{}},
+		{"empty query", "  ", []int{}},
+	}
+
+	index := newTestSearchIndex(t)
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			hits, total, err := index.Search(test.query, 10, 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if total != int64(len(test.want)) {
+				t.Errorf("total = %d, want %d", total, len(test.want))
+			}
+
+			got := []int{}
+			for _, hit := range hits {
+				got = append(got, hit.CampaignID)
+			}
+
+			if len(got) != len(test.want) {
+				t.Fatalf("hits = %v, want %v", got, test.want)
+			}
+
+			for i := range got {
+				if got[i] != test.want[i] {
+					t.Fatalf("hits = %v, want %v", got, test.want)
+				}
+			}
+		})
+	}
+}
+
+func TestMemorySearchIndexPaginates(t *testing.T) {
+	index := newTestSearchIndex(t)
+
+	tests := []struct {
+		limit  int
+		offset int
+		want   []int
+	}{
+		{2, 0, []int{1, 3}},
+		{2, 2, []int{2}},
+		{2, 3, []int{}},
+	}
+
+	for _, test := range tests {
+		hits, total, err := index.Search("solar", test.limit, test.offset)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if total != 3 {
+			t.Errorf("offset %d: total = %d, want 3", test.offset, total)
+		}
+
+		if len(hits) != len(test.want) {
+			t.Fatalf("offset %d: got %d hits, want %d", test.offset, len(hits), len(test.want))
+		}
+
+		for i := range hits {
+			if hits[i].CampaignID != test.want[i] {
+				t.Errorf("offset %d: hit %d = %d, want %d", test.offset, i, hits[i].CampaignID, test.want[i])
+			}
+		}
+	}
+}
+
+func TestMemorySearchIndexDropsCampaignsThatAreNoLongerListed(t *testing.T) {
+	index := newTestSearchIndex(t)
+
+	err := index.Index(Campaign{ID: 1, Status: StatusDraft, Name: "Solar Lamp"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	hits, _, err := index.Search("lamp", 10, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(hits) != 0 {
+		t.Errorf("got %d hits for an unlisted campaign, want 0", len(hits))
+	}
+}
+
+func TestHighlight(t *testing.T) {
+	tests := []struct {
+		name  string
+		text  string
+		terms []string
+		want  string
+	}{
+		{"marks matches", "Light for every village", []string{"village"}, "Light for every <mark>village</mark>"},
+		{"keeps punctuation inside the mark", "Hello, village!", []string{"village"}, "Hello, <mark>village!</mark>"},
+		{"escapes html", `<script>alert("x")</script> village`, []string{"village"}, "&lt;script&gt;alert(&#34;x&#34;)&lt;/script&gt; <mark>village</mark>"},
+		{"escapes a matching word", "<b>village</b>", []string{"village"}, "<mark>&lt;b&gt;village&lt;/b&gt;</mark>"},
+		{"starts a few words before the first match", "one two three four five six seven eight village", []string{"village"}, "four five six seven eight <mark>village</mark>"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := highlight(test.text, test.terms); got != test.want {
+				t.Errorf("highlight = %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestEscapeHeadline(t *testing.T) {
+	headline := "<img src=x onerror=alert(1)> " + headlineStart + "solar" + headlineStop + " & more"
+	want := "&lt;img src=x onerror=alert(1)&gt; <mark>solar</mark> &amp; more"
+
+	if got := escapeHeadline(headline); got != want {
+		t.Errorf("escapeHeadline = %q, want %q", got, want)
+	}
+}
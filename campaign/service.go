@@ -1,44 +1,172 @@
 package campaign
 
 import (
+	"bwastartup/helper"
+	"bwastartup/policy"
+	"bwastartup/user"
 	"errors"
 	"fmt"
+	"time"
 
 	"github.com/gosimple/slug"
 )
 
+// maxSlugAttempts bounds the retries when a slug is taken between checking
+// and saving it.
+const maxSlugAttempts = 5
+
+var (
+	ErrCampaignNotFound     = errors.New("campaign not found")
+	ErrRewardNotFound       = errors.New("reward not found")
+	ErrRewardClaimed        = errors.New("reward has already been claimed")
+	ErrRewardQuantityTooLow = errors.New("quantity is lower than the number of claimed rewards")
+	ErrImageNotFound        = errors.New("campaign image not found")
+	ErrInvalidImageOrder    = errors.New("image_ids must list every image of the campaign once")
+)
+
 type Service interface {
-	GetCampaigns(userID int) ([]Campaign, error)
+	GetCampaigns(input GetCampaignsInput) ([]Campaign, helper.Pagination, error)
+	SearchCampaigns(input SearchCampaignsInput) ([]SearchResult, helper.Pagination, error)
 	GetCampaignByID(input GetCampaignDetailInput) (Campaign, error)
+	GetCampaignBySlug(input GetCampaignBySlugInput) (Campaign, bool, error)
 	CreateCampaign(input CreateCampaignInput) (Campaign, error)
 	UpdateCampaign(inputID GetCampaignDetailInput, inputData CreateCampaignInput) (Campaign, error)
-	SaveCampaignImage(input CreateCampaignImageInput, fileLocation string) (CampaignImage, error)
+	AuthorizeImageUpload(input CreateCampaignImageInput) error
+	SaveCampaignImage(input CreateCampaignImageInput, files CampaignImageFiles) (CampaignImage, error)
+	UnpublishCampaign(input GetCampaignDetailInput, currentUser user.User) (Campaign, error)
+	PublishCampaign(input ChangeCampaignStatusInput) (Campaign, error)
+	CloseCampaign(input ChangeCampaignStatusInput) (Campaign, error)
+	ExpireCampaigns(now time.Time) (int, error)
+	GetRewards(input GetCampaignDetailInput) ([]Reward, error)
+	CreateReward(inputID GetCampaignDetailInput, inputData RewardInput) (Reward, error)
+	UpdateReward(inputID GetRewardInput, inputData RewardInput) (Reward, error)
+	DeleteReward(input GetRewardInput) error
+	DeleteCampaignImage(input GetCampaignImageInput) (CampaignImage, error)
+	SetPrimaryCampaignImage(input GetCampaignImageInput) (CampaignImage, error)
+	ReorderCampaignImages(inputID GetCampaignDetailInput, inputData ReorderCampaignImagesInput) ([]CampaignImage, error)
 }
 
 type service struct {
-	repository Repository
+	repository  Repository
+	searchIndex SearchIndex
 }
 
-func NewService(repository Repository) *service {
-	return &service{repository}
+func NewService(repository Repository, searchIndex SearchIndex) *service {
+	return &service{repository, searchIndex}
 }
 
-func (s *service) GetCampaigns(userID int) ([]Campaign, error) {
-	if userID != 0 {
-		campaigns, err := s.repository.FIndByUserID(userID)
+// GetCampaigns pages either by page number or, when a cursor from a
+// previous response is given, by cursor. Both modes return the cursor of
+// the next page so clients can switch to infinite scrolling.
+func (s *service) GetCampaigns(input GetCampaignsInput) ([]Campaign, helper.Pagination, error) {
+	query := CampaignQuery{}
+	query.UserID = input.UserID
+	query.FundingStatus = input.FundingStatus
+	query.MinGoal = input.MinGoal
+	query.MaxGoal = input.MaxGoal
+	query.Sort = input.Sort
+	query.Limit = input.Limit
+
+	if query.Sort == "" {
+		query.Sort = SortNewest
+	}
+
+	if query.Limit == 0 {
+		query.Limit = DefaultLimit
+	}
+
+	page := input.Page
+	if input.Cursor != "" {
+		cursor, err := DecodeCursor(input.Cursor, query.Sort)
 		if err != nil {
-			return campaigns, err
+			return []Campaign{}, helper.Pagination{}, err
 		}
 
-		return campaigns, nil
+		query.Cursor = cursor
+		page = 0
+	} else {
+		if page == 0 {
+			page = 1
+		}
+
+		query.Offset = (page - 1) * query.Limit
 	}
 
-	campaigns, err := s.repository.FindAll()
+	total, err := s.repository.CountByQuery(query)
 	if err != nil {
-		return campaigns, err
+		return []Campaign{}, helper.Pagination{}, err
 	}
 
-	return campaigns, nil
+	limit := query.Limit
+	query.Limit = limit + 1
+
+	campaigns, err := s.repository.FindByQuery(query)
+	if err != nil {
+		return campaigns, helper.Pagination{}, err
+	}
+
+	pagination := helper.NewPagination(page, limit, total)
+
+	if len(campaigns) > limit {
+		campaigns = campaigns[:limit]
+		pagination.HasMore = true
+		pagination.NextCursor = EncodeCursor(campaigns[limit-1], query.Sort)
+	}
+
+	return campaigns, pagination, nil
+}
+
+func (s *service) SearchCampaigns(input SearchCampaignsInput) ([]SearchResult, helper.Pagination, error) {
+	limit := input.Limit
+	if limit == 0 {
+		limit = DefaultLimit
+	}
+
+	page := input.Page
+	if page == 0 {
+		page = 1
+	}
+
+	hits, total, err := s.searchIndex.Search(input.Query, limit, (page-1)*limit)
+	if err != nil {
+		return []SearchResult{}, helper.Pagination{}, err
+	}
+
+	pagination := helper.NewPagination(page, limit, total)
+	pagination.HasMore = page < pagination.TotalPages
+
+	if len(hits) == 0 {
+		return []SearchResult{}, pagination, nil
+	}
+
+	var campaignIDs []int
+	for _, hit := range hits {
+		campaignIDs = append(campaignIDs, hit.CampaignID)
+	}
+
+	campaigns, err := s.repository.FindByIDs(campaignIDs)
+	if err != nil {
+		return []SearchResult{}, pagination, err
+	}
+
+	campaignsByID := map[int]Campaign{}
+	for _, campaign := range campaigns {
+		campaignsByID[campaign.ID] = campaign
+	}
+
+	// Keep the ranking of the index and skip campaigns deleted since they
+	// were indexed.
+	results := []SearchResult{}
+	for _, hit := range hits {
+		campaign, ok := campaignsByID[hit.CampaignID]
+		if !ok {
+			continue
+		}
+
+		results = append(results, SearchResult{Campaign: campaign, Rank: hit.Rank, Highlight: hit.Highlight})
+	}
+
+	return results, pagination, nil
 }
 
 func (s *service) GetCampaignByID(input GetCampaignDetailInput) (Campaign, error) {
@@ -47,22 +175,87 @@ func (s *service) GetCampaignByID(input GetCampaignDetailInput) (Campaign, error
 		return campaign, err
 	}
 
+	if campaign.ID == 0 || !canView(campaign, input.User) {
+		return Campaign{}, ErrCampaignNotFound
+	}
+
 	return campaign, nil
 }
 
+// canView reports whether the user may read the campaign. Drafts and
+// unpublished campaigns are only visible to their owner and admins.
+func canView(campaign Campaign, currentUser user.User) bool {
+	return campaign.IsListed() || policy.CanManage(currentUser, campaign.UserID)
+}
+
+// GetCampaignBySlug also finds campaigns by a slug they had before being
+// renamed, and then reports that the slug has moved.
+func (s *service) GetCampaignBySlug(input GetCampaignBySlugInput) (Campaign, bool, error) {
+	campaign, err := s.repository.FindBySlug(input.Slug)
+	if err != nil {
+		return campaign, false, err
+	}
+
+	moved := false
+	if campaign.ID == 0 {
+		campaignSlug, err := s.repository.FindSlugHistory(input.Slug)
+		if err != nil {
+			return Campaign{}, false, err
+		}
+
+		if campaignSlug.ID == 0 {
+			return Campaign{}, false, ErrCampaignNotFound
+		}
+
+		campaign, err = s.repository.FindByID(campaignSlug.CampaignID)
+		if err != nil {
+			return campaign, false, err
+		}
+
+		moved = true
+	}
+
+	if campaign.ID == 0 || !canView(campaign, input.User) {
+		return Campaign{}, false, ErrCampaignNotFound
+	}
+
+	return campaign, moved, nil
+}
+
 func (s *service) CreateCampaign(input CreateCampaignInput) (Campaign, error) {
 	campaign := Campaign{}
 	campaign.Name = input.Name
 	campaign.ShortDescription = input.ShortDescription
 	campaign.Description = input.Description
 	campaign.GoalAmount = input.GoalAmount
-	campaign.Perks = input.Perks
 	campaign.UserID = input.User.ID
+	campaign.Status = StatusDraft
 
-	slugCandidate := fmt.Sprintf("%s %d", input.Name, input.User.ID)
-	campaign.Slug = slug.Make(slugCandidate)
+	if !input.Deadline.After(time.Now()) {
+		return campaign, ErrDeadlineInPast
+	}
+	campaign.Deadline = &input.Deadline
 
-	newCampaign, err := s.repository.Save(campaign)
+	var newCampaign Campaign
+	var err error
+	for attempt := 0; attempt < maxSlugAttempts; attempt++ {
+		campaign.Slug, err = s.uniqueSlug(campaign.Name, 0)
+		if err != nil {
+			return campaign, err
+		}
+
+		// Somebody else may have taken the slug since it was checked.
+		newCampaign, err = s.repository.Save(campaign)
+		if !helper.IsUniqueViolation(err) {
+			break
+		}
+	}
+
+	if err != nil {
+		return newCampaign, err
+	}
+
+	err = s.searchIndex.Index(newCampaign)
 	if err != nil {
 		return newCampaign, err
 	}
@@ -76,17 +269,59 @@ func (s *service) UpdateCampaign(inputID GetCampaignDetailInput, inputData Creat
 		return campaign, err
 	}
 
-	if campaign.UserID != inputData.User.ID {
-		return campaign, errors.New("not an owner of campaigns")
+	if campaign.ID == 0 {
+		return campaign, ErrCampaignNotFound
 	}
 
+	if !policy.CanManage(inputData.User, campaign.UserID) {
+		return campaign, policy.ErrNotOwner
+	}
+
+	oldSlug := campaign.Slug
+	renamed := campaign.Name != inputData.Name
+
 	campaign.Name = inputData.Name
 	campaign.ShortDescription = inputData.ShortDescription
 	campaign.Description = inputData.Description
 	campaign.GoalAmount = inputData.GoalAmount
-	campaign.Perks = inputData.Perks
 
-	newCampaign, err := s.repository.Update(campaign)
+	if campaign.Deadline == nil || !campaign.Deadline.Equal(inputData.Deadline) {
+		if campaign.Status != StatusDraft {
+			return campaign, ErrDeadlineLocked
+		}
+
+		if !inputData.Deadline.After(time.Now()) {
+			return campaign, ErrDeadlineInPast
+		}
+
+		campaign.Deadline = &inputData.Deadline
+	}
+
+	var newCampaign Campaign
+	for attempt := 0; attempt < maxSlugAttempts; attempt++ {
+		if renamed {
+			campaign.Slug, err = s.uniqueSlug(campaign.Name, campaign.ID)
+			if err != nil {
+				return campaign, err
+			}
+		}
+
+		if !renamed {
+			newCampaign, err = s.repository.Update(campaign)
+			break
+		}
+
+		newCampaign, err = s.repository.UpdateWithSlugHistory(campaign, oldSlug)
+		if !helper.IsUniqueViolation(err) {
+			break
+		}
+	}
+
+	if err != nil {
+		return newCampaign, err
+	}
+
+	err = s.searchIndex.Index(newCampaign)
 	if err != nil {
 		return newCampaign, err
 	}
@@ -94,14 +329,37 @@ func (s *service) UpdateCampaign(inputID GetCampaignDetailInput, inputData Creat
 	return newCampaign, nil
 }
 
-func (s *service) SaveCampaignImage(input CreateCampaignImageInput, fileLocation string) (CampaignImage, error) {
+// AuthorizeImageUpload checks that the user may add images to the campaign,
+// before any file is processed or stored.
+func (s *service) AuthorizeImageUpload(input CreateCampaignImageInput) error {
+	campaign, err := s.repository.FindByID(input.CampaignID)
+	if err != nil {
+		return err
+	}
+
+	if campaign.ID == 0 {
+		return ErrCampaignNotFound
+	}
+
+	if !policy.CanManage(input.User, campaign.UserID) {
+		return policy.ErrNotOwner
+	}
+
+	return nil
+}
+
+func (s *service) SaveCampaignImage(input CreateCampaignImageInput, files CampaignImageFiles) (CampaignImage, error) {
 	campaign, err := s.repository.FindByID(input.CampaignID)
 	if err != nil {
 		return CampaignImage{}, err
 	}
 
-	if campaign.UserID != input.User.ID {
-		return CampaignImage{}, errors.New("not an owner of campaigns")
+	if campaign.ID == 0 {
+		return CampaignImage{}, ErrCampaignNotFound
+	}
+
+	if !policy.CanManage(input.User, campaign.UserID) {
+		return CampaignImage{}, policy.ErrNotOwner
 	}
 
 	isPrimary := 0
@@ -116,7 +374,14 @@ func (s *service) SaveCampaignImage(input CreateCampaignImageInput, fileLocation
 	campaignImage := CampaignImage{}
 	campaignImage.CampaignID = input.CampaignID
 	campaignImage.IsPrimary = isPrimary
-	campaignImage.FileName = fileLocation
+	campaignImage.FileName = files.Full
+	campaignImage.CardFileName = files.Card
+	campaignImage.ThumbnailFileName = files.Thumbnail
+
+	campaignImage.Position, err = s.repository.NextImagePosition(input.CampaignID)
+	if err != nil {
+		return campaignImage, err
+	}
 
 	newCampaignImage, err := s.repository.CreateImage(campaignImage)
 	if err != nil {
@@ -124,3 +389,411 @@ func (s *service) SaveCampaignImage(input CreateCampaignImageInput, fileLocation
 	}
 	return newCampaignImage, nil
 }
+
+// DeleteCampaignImage removes the image record and returns it, so the caller
+// can delete the stored files. When the primary image is deleted the first
+// remaining image becomes primary.
+func (s *service) DeleteCampaignImage(input GetCampaignImageInput) (CampaignImage, error) {
+	campaignImage, err := s.findCampaignImage(input)
+	if err != nil {
+		return campaignImage, err
+	}
+
+	err = s.repository.DeleteImage(campaignImage)
+	if err != nil {
+		return campaignImage, err
+	}
+
+	if campaignImage.IsPrimary == 1 {
+		remainingImages, err := s.repository.FindImagesByCampaignID(campaignImage.CampaignID)
+		if err != nil {
+			return campaignImage, err
+		}
+
+		if len(remainingImages) > 0 {
+			err = s.repository.SetPrimaryImage(campaignImage.CampaignID, remainingImages[0].ID)
+			if err != nil {
+				return campaignImage, err
+			}
+		}
+	}
+
+	return campaignImage, nil
+}
+
+func (s *service) SetPrimaryCampaignImage(input GetCampaignImageInput) (CampaignImage, error) {
+	campaignImage, err := s.findCampaignImage(input)
+	if err != nil {
+		return campaignImage, err
+	}
+
+	err = s.repository.SetPrimaryImage(campaignImage.CampaignID, campaignImage.ID)
+	if err != nil {
+		return campaignImage, err
+	}
+
+	campaignImage.IsPrimary = 1
+
+	return campaignImage, nil
+}
+
+// ReorderCampaignImages takes the ids of all images of the campaign in their
+// new order.
+func (s *service) ReorderCampaignImages(inputID GetCampaignDetailInput, inputData ReorderCampaignImagesInput) ([]CampaignImage, error) {
+	campaign, err := s.repository.FindByID(inputID.ID)
+	if err != nil {
+		return []CampaignImage{}, err
+	}
+
+	if campaign.ID == 0 {
+		return []CampaignImage{}, ErrCampaignNotFound
+	}
+
+	if !policy.CanManage(inputData.User, campaign.UserID) {
+		return []CampaignImage{}, policy.ErrNotOwner
+	}
+
+	if len(inputData.ImageIDs) != len(campaign.CampaignImages) {
+		return []CampaignImage{}, ErrInvalidImageOrder
+	}
+
+	imageIDs := map[int]bool{}
+	for _, campaignImage := range campaign.CampaignImages {
+		imageIDs[campaignImage.ID] = true
+	}
+
+	for _, imageID := range inputData.ImageIDs {
+		if !imageIDs[imageID] {
+			return []CampaignImage{}, ErrInvalidImageOrder
+		}
+
+		// Each id may only appear once.
+		delete(imageIDs, imageID)
+	}
+
+	err = s.repository.UpdateImagePositions(campaign.ID, inputData.ImageIDs)
+	if err != nil {
+		return []CampaignImage{}, err
+	}
+
+	campaignImages, err := s.repository.FindImagesByCampaignID(campaign.ID)
+	if err != nil {
+		return campaignImages, err
+	}
+
+	return campaignImages, nil
+}
+
+func (s *service) findCampaignImage(input GetCampaignImageInput) (CampaignImage, error) {
+	campaign, err := s.repository.FindByID(input.ID)
+	if err != nil {
+		return CampaignImage{}, err
+	}
+
+	campaignImage, err := s.repository.FindImageByID(input.ImageID)
+	if err != nil {
+		return campaignImage, err
+	}
+
+	if campaign.ID == 0 || campaignImage.ID == 0 || campaignImage.CampaignID != campaign.ID {
+		return CampaignImage{}, ErrImageNotFound
+	}
+
+	if !policy.CanManage(input.User, campaign.UserID) {
+		return CampaignImage{}, policy.ErrNotOwner
+	}
+
+	return campaignImage, nil
+}
+
+// UnpublishCampaign is a moderation action: the campaign disappears from
+// every public listing but its data and transactions are kept.
+func (s *service) UnpublishCampaign(input GetCampaignDetailInput, currentUser user.User) (Campaign, error) {
+	if !policy.IsAdmin(currentUser) {
+		return Campaign{}, policy.ErrNotAdmin
+	}
+
+	campaign, err := s.repository.FindByID(input.ID)
+	if err != nil {
+		return campaign, err
+	}
+
+	if campaign.ID == 0 {
+		return campaign, ErrCampaignNotFound
+	}
+
+	now := time.Now()
+	campaign.UnpublishedAt = &now
+
+	updatedCampaign, err := s.repository.Update(campaign)
+	if err != nil {
+		return updatedCampaign, err
+	}
+
+	err = s.searchIndex.Index(updatedCampaign)
+	if err != nil {
+		return updatedCampaign, err
+	}
+
+	return updatedCampaign, nil
+}
+
+func (s *service) PublishCampaign(input ChangeCampaignStatusInput) (Campaign, error) {
+	campaign, err := s.repository.FindByID(input.ID)
+	if err != nil {
+		return campaign, err
+	}
+
+	if campaign.ID == 0 {
+		return campaign, ErrCampaignNotFound
+	}
+
+	if !policy.CanManage(input.User, campaign.UserID) {
+		return campaign, policy.ErrNotOwner
+	}
+
+	if campaign.Deadline == nil || !campaign.Deadline.After(time.Now()) {
+		return campaign, ErrDeadlineInPast
+	}
+
+	return s.changeStatus(campaign, StatusPublished)
+}
+
+// CloseCampaign archives a campaign once its outcome is known.
+func (s *service) CloseCampaign(input ChangeCampaignStatusInput) (Campaign, error) {
+	campaign, err := s.repository.FindByID(input.ID)
+	if err != nil {
+		return campaign, err
+	}
+
+	if campaign.ID == 0 {
+		return campaign, ErrCampaignNotFound
+	}
+
+	if !policy.CanManage(input.User, campaign.UserID) {
+		return campaign, policy.ErrNotOwner
+	}
+
+	return s.changeStatus(campaign, StatusClosed)
+}
+
+// ExpireCampaigns ends every published campaign whose deadline is not after
+// now. It is funded when it reached its goal and failed otherwise. It returns
+// how many campaigns it ended.
+func (s *service) ExpireCampaigns(now time.Time) (int, error) {
+	campaigns, err := s.repository.FindExpired(now)
+	if err != nil {
+		return 0, err
+	}
+
+	expired := 0
+	for _, campaign := range campaigns {
+		status := StatusFailed
+		if campaign.CurrentAmount >= campaign.GoalAmount {
+			status = StatusFunded
+		}
+
+		_, err := s.changeStatus(campaign, status)
+		if err == ErrInvalidTransition {
+			// Another instance got there first.
+			continue
+		}
+
+		if err != nil {
+			return expired, err
+		}
+
+		expired++
+	}
+
+	return expired, nil
+}
+
+func (s *service) GetRewards(input GetCampaignDetailInput) ([]Reward, error) {
+	campaign, err := s.repository.FindByID(input.ID)
+	if err != nil {
+		return []Reward{}, err
+	}
+
+	if campaign.ID == 0 || !canView(campaign, input.User) {
+		return []Reward{}, ErrCampaignNotFound
+	}
+
+	rewards, err := s.repository.FindRewardsByCampaignID(input.ID)
+	if err != nil {
+		return rewards, err
+	}
+
+	return rewards, nil
+}
+
+func (s *service) CreateReward(inputID GetCampaignDetailInput, inputData RewardInput) (Reward, error) {
+	campaign, err := s.repository.FindByID(inputID.ID)
+	if err != nil {
+		return Reward{}, err
+	}
+
+	if campaign.ID == 0 {
+		return Reward{}, ErrCampaignNotFound
+	}
+
+	if !policy.CanManage(inputData.User, campaign.UserID) {
+		return Reward{}, policy.ErrNotOwner
+	}
+
+	reward := Reward{}
+	reward.CampaignID = campaign.ID
+	reward.Title = inputData.Title
+	reward.Description = inputData.Description
+	reward.MinimumPledge = inputData.MinimumPledge
+	reward.Quantity = inputData.Quantity
+	reward.EstimatedDelivery = inputData.EstimatedDelivery
+
+	newReward, err := s.repository.CreateReward(reward)
+	if err != nil {
+		return newReward, err
+	}
+
+	campaign.Rewards = append(campaign.Rewards, newReward)
+
+	err = s.searchIndex.Index(campaign)
+	if err != nil {
+		return newReward, err
+	}
+
+	return newReward, nil
+}
+
+func (s *service) UpdateReward(inputID GetRewardInput, inputData RewardInput) (Reward, error) {
+	campaign, reward, err := s.findReward(inputID.ID, inputID.RewardID, inputData.User)
+	if err != nil {
+		return reward, err
+	}
+
+	if inputData.Quantity != nil && *inputData.Quantity < reward.ClaimedCount {
+		return reward, ErrRewardQuantityTooLow
+	}
+
+	reward.Title = inputData.Title
+	reward.Description = inputData.Description
+	reward.MinimumPledge = inputData.MinimumPledge
+	reward.Quantity = inputData.Quantity
+	reward.EstimatedDelivery = inputData.EstimatedDelivery
+
+	updatedReward, err := s.repository.UpdateReward(reward)
+	if err != nil {
+		return updatedReward, err
+	}
+
+	for i := range campaign.Rewards {
+		if campaign.Rewards[i].ID == updatedReward.ID {
+			campaign.Rewards[i] = updatedReward
+		}
+	}
+
+	err = s.searchIndex.Index(campaign)
+	if err != nil {
+		return updatedReward, err
+	}
+
+	return updatedReward, nil
+}
+
+// DeleteReward refuses to delete a reward somebody already claimed, since
+// their pledge still points at it.
+func (s *service) DeleteReward(input GetRewardInput) error {
+	campaign, reward, err := s.findReward(input.ID, input.RewardID, input.User)
+	if err != nil {
+		return err
+	}
+
+	if reward.ClaimedCount > 0 {
+		return ErrRewardClaimed
+	}
+
+	err = s.repository.DeleteReward(reward)
+	if err != nil {
+		return err
+	}
+
+	rewards := []Reward{}
+	for _, campaignReward := range campaign.Rewards {
+		if campaignReward.ID != reward.ID {
+			rewards = append(rewards, campaignReward)
+		}
+	}
+	campaign.Rewards = rewards
+
+	return s.searchIndex.Index(campaign)
+}
+
+// findReward loads a reward together with its campaign, making sure the
+// reward belongs to that campaign and currentUser may manage it.
+func (s *service) findReward(campaignID int, rewardID int, currentUser user.User) (Campaign, Reward, error) {
+	campaign, err := s.repository.FindByID(campaignID)
+	if err != nil {
+		return campaign, Reward{}, err
+	}
+
+	reward, err := s.repository.FindRewardByID(rewardID)
+	if err != nil {
+		return campaign, reward, err
+	}
+
+	if campaign.ID == 0 || reward.ID == 0 || reward.CampaignID != campaign.ID {
+		return campaign, Reward{}, ErrRewardNotFound
+	}
+
+	if !policy.CanManage(currentUser, campaign.UserID) {
+		return campaign, Reward{}, policy.ErrNotOwner
+	}
+
+	return campaign, reward, nil
+}
+
+// uniqueSlug derives a slug from name that no other campaign uses or used,
+// adding -2, -3, ... when the plain slug is taken.
+func (s *service) uniqueSlug(name string, campaignID int) (string, error) {
+	base := slug.Make(name)
+	if base == "" {
+		base = "campaign"
+	}
+
+	candidate := base
+	for suffix := 2; ; suffix++ {
+		taken, err := s.repository.IsSlugTaken(candidate, campaignID)
+		if err != nil {
+			return "", err
+		}
+
+		if !taken {
+			return candidate, nil
+		}
+
+		candidate = fmt.Sprintf("%s-%d", base, suffix)
+	}
+}
+
+func (s *service) changeStatus(campaign Campaign, status string) (Campaign, error) {
+	if !canTransition(campaign.Status, status) {
+		return campaign, ErrInvalidTransition
+	}
+
+	updated, err := s.repository.UpdateStatus(campaign.ID, campaign.Status, status)
+	if err != nil {
+		return campaign, err
+	}
+
+	if !updated {
+		return campaign, ErrInvalidTransition
+	}
+
+	campaign.Status = status
+
+	err = s.searchIndex.Index(campaign)
+	if err != nil {
+		return campaign, err
+	}
+
+	return campaign, nil
+}
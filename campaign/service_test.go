@@ -0,0 +1,249 @@
+package campaign
+
+import (
+	"bwastartup/user"
+	"testing"
+	"time"
+)
+
+// memoryRepository keeps campaigns and their slug history in memory. Methods
+// the tests do not use fall through to the embedded nil Repository.
+type memoryRepository struct {
+	Repository
+	campaigns []Campaign
+	slugs     []CampaignSlug
+}
+
+func (r *memoryRepository) FindByID(ID int) (Campaign, error) {
+	for _, campaign := range r.campaigns {
+		if campaign.ID == ID {
+			return campaign, nil
+		}
+	}
+
+	return Campaign{}, nil
+}
+
+func (r *memoryRepository) FindBySlug(slug string) (Campaign, error) {
+	for _, campaign := range r.campaigns {
+		if campaign.Slug == slug {
+			return campaign, nil
+		}
+	}
+
+	return Campaign{}, nil
+}
+
+func (r *memoryRepository) FindSlugHistory(slug string) (CampaignSlug, error) {
+	for _, campaignSlug := range r.slugs {
+		if campaignSlug.Slug == slug {
+			return campaignSlug, nil
+		}
+	}
+
+	return CampaignSlug{}, nil
+}
+
+func (r *memoryRepository) IsSlugTaken(slug string, campaignID int) (bool, error) {
+	for _, campaign := range r.campaigns {
+		if campaign.Slug == slug && campaign.ID != campaignID {
+			return true, nil
+		}
+	}
+
+	for _, campaignSlug := range r.slugs {
+		if campaignSlug.Slug == slug && campaignSlug.CampaignID != campaignID {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+func (r *memoryRepository) Save(campaign Campaign) (Campaign, error) {
+	campaign.ID = len(r.campaigns) + 1
+	r.campaigns = append(r.campaigns, campaign)
+
+	return campaign, nil
+}
+
+func (r *memoryRepository) Update(campaign Campaign) (Campaign, error) {
+	for i := range r.campaigns {
+		if r.campaigns[i].ID == campaign.ID {
+			r.campaigns[i] = campaign
+		}
+	}
+
+	return campaign, nil
+}
+
+func (r *memoryRepository) UpdateWithSlugHistory(campaign Campaign, oldSlug string) (Campaign, error) {
+	campaign, err := r.Update(campaign)
+	if err != nil || campaign.Slug == oldSlug {
+		return campaign, err
+	}
+
+	slugs := []CampaignSlug{}
+	for _, campaignSlug := range r.slugs {
+		if campaignSlug.CampaignID != campaign.ID || campaignSlug.Slug != campaign.Slug {
+			slugs = append(slugs, campaignSlug)
+		}
+	}
+	r.slugs = append(slugs, CampaignSlug{ID: len(r.slugs) + 1, CampaignID: campaign.ID, Slug: oldSlug})
+
+	return campaign, nil
+}
+
+func newTestService() (*service, *memoryRepository) {
+	repository := &memoryRepository{}
+	return NewService(repository, NewMemorySearchIndex()), repository
+}
+
+func newTestCampaignInput(name string, owner user.User) CreateCampaignInput {
+	return CreateCampaignInput{
+		Name:             name,
+		ShortDescription: "Short",
+		Description:      "Long",
+		GoalAmount:       1000,
+		Deadline:         time.Now().Add(24 * time.Hour).Truncate(time.Second),
+		User:             owner,
+	}
+}
+
+func TestCreateCampaignSlug(t *testing.T) {
+	owner := user.User{ID: 1, Role: user.RoleUser}
+
+	tests := []struct {
+		name     string
+		existing []string
+		history  []string
+		want     string
+	}{
+		{"plain slug", nil, nil, "solar-lamp"},
+		{"taken by another campaign", []string{"Solar Lamp"}, nil, "solar-lamp-2"},
+		{"taken twice", []string{"Solar Lamp", "Solar Lamp"}, nil, "solar-lamp-3"},
+		{"reserved by a renamed campaign", nil, []string{"solar-lamp"}, "solar-lamp-2"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			service, repository := newTestService()
+
+			for _, name := range test.existing {
+				_, err := service.CreateCampaign(newTestCampaignInput(name, owner))
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			for _, slug := range test.history {
+				repository.slugs = append(repository.slugs, CampaignSlug{ID: len(repository.slugs) + 1, CampaignID: 99, Slug: slug})
+			}
+
+			campaign, err := service.CreateCampaign(newTestCampaignInput("Solar Lamp", owner))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if campaign.Slug != test.want {
+				t.Errorf("Slug = %q, want %q", campaign.Slug, test.want)
+			}
+		})
+	}
+}
+
+func TestRenamedCampaignKeepsItsOldSlug(t *testing.T) {
+	owner := user.User{ID: 1, Role: user.RoleUser}
+	service, _ := newTestService()
+
+	created, err := service.CreateCampaign(newTestCampaignInput("Solar Lamp", owner))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = service.UpdateCampaign(GetCampaignDetailInput{ID: created.ID}, newTestCampaignInput("Moon Lamp", owner))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		slug      string
+		wantMoved bool
+	}{
+		{"moon-lamp", false},
+		{"solar-lamp", true},
+	}
+
+	for _, test := range tests {
+		campaign, moved, err := service.GetCampaignBySlug(GetCampaignBySlugInput{Slug: test.slug, User: owner})
+		if err != nil {
+			t.Fatalf("%s: %v", test.slug, err)
+		}
+
+		if campaign.ID != created.ID || moved != test.wantMoved {
+			t.Errorf("%s: got campaign %d, moved %v, want campaign %d, moved %v", test.slug, campaign.ID, moved, created.ID, test.wantMoved)
+		}
+	}
+
+	other, err := service.CreateCampaign(newTestCampaignInput("Solar Lamp", owner))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if other.Slug != "solar-lamp-2" {
+		t.Errorf("new campaign took the old slug: Slug = %q", other.Slug)
+	}
+
+	renamedBack, err := service.UpdateCampaign(GetCampaignDetailInput{ID: created.ID}, newTestCampaignInput("Solar Lamp", owner))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if renamedBack.Slug != "solar-lamp" {
+		t.Errorf("renaming back: Slug = %q, want %q", renamedBack.Slug, "solar-lamp")
+	}
+
+	_, moved, err := service.GetCampaignBySlug(GetCampaignBySlugInput{Slug: "moon-lamp", User: owner})
+	if err != nil || !moved {
+		t.Errorf("moon-lamp after renaming back: moved = %v, err = %v, want a redirect", moved, err)
+	}
+}
+
+func TestGetCampaignBySlugHidesDrafts(t *testing.T) {
+	owner := user.User{ID: 1, Role: user.RoleUser}
+	service, _ := newTestService()
+
+	_, err := service.CreateCampaign(newTestCampaignInput("Solar Lamp", owner))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		user    user.User
+		wantErr error
+	}{
+		{"owner", owner, nil},
+		{"admin", user.User{ID: 2, Role: user.RoleAdmin}, nil},
+		{"other user", user.User{ID: 3, Role: user.RoleUser}, ErrCampaignNotFound},
+		{"anonymous", user.User{}, ErrCampaignNotFound},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, _, err := service.GetCampaignBySlug(GetCampaignBySlugInput{Slug: "solar-lamp", User: test.user})
+			if err != test.wantErr {
+				t.Errorf("err = %v, want %v", err, test.wantErr)
+			}
+		})
+	}
+}
+
+func TestGetCampaignBySlugUnknownSlug(t *testing.T) {
+	service, _ := newTestService()
+
+	_, _, err := service.GetCampaignBySlug(GetCampaignBySlugInput{Slug: "nothing-here"})
+	if err != ErrCampaignNotFound {
+		t.Errorf("err = %v, want ErrCampaignNotFound", err)
+	}
+}
@@ -0,0 +1,20 @@
+package campaignupdate
+
+import (
+	"bwastartup/user"
+	"time"
+)
+
+// CampaignUpdate is a news post written by the campaign owner. Posts with
+// BackersOnly set are only shown to people who paid for the campaign.
+type CampaignUpdate struct {
+	ID          int
+	CampaignID  int `gorm:"index"`
+	UserID      int
+	Title       string
+	Body        string
+	BackersOnly bool
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	User        user.User
+}
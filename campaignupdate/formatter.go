@@ -0,0 +1,46 @@
+package campaignupdate
+
+import "time"
+
+type CampaignUpdateFormatter struct {
+	ID          int                         `json:"id"`
+	CampaignID  int                         `json:"campaign_id"`
+	Title       string                      `json:"title"`
+	Body        string                      `json:"body"`
+	BackersOnly bool                        `json:"backers_only"`
+	CreatedAt   time.Time                   `json:"created_at"`
+	User        CampaignUpdateUserFormatter `json:"user"`
+}
+
+type CampaignUpdateUserFormatter struct {
+	Name     string `json:"name"`
+	ImageURL string `json:"image_url"`
+}
+
+func FormatCampaignUpdate(campaignUpdate CampaignUpdate) CampaignUpdateFormatter {
+	formatter := CampaignUpdateFormatter{}
+	formatter.ID = campaignUpdate.ID
+	formatter.CampaignID = campaignUpdate.CampaignID
+	formatter.Title = campaignUpdate.Title
+	formatter.Body = campaignUpdate.Body
+	formatter.BackersOnly = campaignUpdate.BackersOnly
+	formatter.CreatedAt = campaignUpdate.CreatedAt
+
+	userFormatter := CampaignUpdateUserFormatter{}
+	userFormatter.Name = campaignUpdate.User.Name
+	userFormatter.ImageURL = campaignUpdate.User.AvatarFileName
+
+	formatter.User = userFormatter
+
+	return formatter
+}
+
+func FormatCampaignUpdates(campaignUpdates []CampaignUpdate) []CampaignUpdateFormatter {
+	formatters := []CampaignUpdateFormatter{}
+
+	for _, campaignUpdate := range campaignUpdates {
+		formatters = append(formatters, FormatCampaignUpdate(campaignUpdate))
+	}
+
+	return formatters
+}
@@ -0,0 +1,14 @@
+package campaignupdate
+
+import "bwastartup/user"
+
+type GetCampaignUpdatesInput struct {
+	ID int `uri:"id" binding:"required"`
+}
+
+type CreateCampaignUpdateInput struct {
+	Title       string `json:"title" binding:"required,excludesall=\x00\t\n\v\f\r\x7f"`
+	Body        string `json:"body" binding:"required"`
+	BackersOnly bool   `json:"backers_only"`
+	User        user.User
+}
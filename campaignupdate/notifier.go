@@ -0,0 +1,42 @@
+package campaignupdate
+
+import (
+	"bwastartup/campaign"
+	"bwastartup/mailer"
+	"bwastartup/user"
+	"fmt"
+)
+
+// Notifier tells backers about a new update of a campaign they backed.
+type Notifier interface {
+	NotifyBackers(campaign campaign.Campaign, campaignUpdate CampaignUpdate, backers []user.User) error
+}
+
+type mailNotifier struct {
+	mailer mailer.Mailer
+	appURL string
+}
+
+func NewMailNotifier(mailer mailer.Mailer, appURL string) *mailNotifier {
+	return &mailNotifier{mailer, appURL}
+}
+
+// NotifyBackers mails every backer and keeps going when one of the mails
+// fails, returning the first error.
+func (n *mailNotifier) NotifyBackers(campaign campaign.Campaign, campaignUpdate CampaignUpdate, backers []user.User) error {
+	var firstErr error
+
+	for _, backer := range backers {
+		message := mailer.Message{}
+		message.To = backer.Email
+		message.Subject = fmt.Sprintf("New update on %s: %s", campaign.Name, campaignUpdate.Title)
+		message.Body = fmt.Sprintf("Hi %s,\n\n%s posted an update on a campaign you backed:\n\n%s\n\nRead it at %s/campaigns/%d/updates\n", backer.Name, campaign.Name, campaignUpdate.Title, n.appURL, campaign.ID)
+
+		err := n.mailer.Send(message)
+		if err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+
+	return firstErr
+}
@@ -0,0 +1,41 @@
+package campaignupdate
+
+import "gorm.io/gorm"
+
+type Repository interface {
+	FindByCampaignID(campaignID int, includeBackersOnly bool) ([]CampaignUpdate, error)
+	Save(campaignUpdate CampaignUpdate) (CampaignUpdate, error)
+}
+
+type repository struct {
+	db *gorm.DB
+}
+
+func NewRepository(db *gorm.DB) *repository {
+	return &repository{db}
+}
+
+func (r *repository) FindByCampaignID(campaignID int, includeBackersOnly bool) ([]CampaignUpdate, error) {
+	var campaignUpdates []CampaignUpdate
+
+	db := r.db.Where("campaign_id = ?", campaignID)
+	if !includeBackersOnly {
+		db = db.Where("backers_only = ?", false)
+	}
+
+	err := db.Preload("User").Order("id desc").Find(&campaignUpdates).Error
+	if err != nil {
+		return campaignUpdates, err
+	}
+
+	return campaignUpdates, nil
+}
+
+func (r *repository) Save(campaignUpdate CampaignUpdate) (CampaignUpdate, error) {
+	err := r.db.Create(&campaignUpdate).Error
+	if err != nil {
+		return campaignUpdate, err
+	}
+
+	return campaignUpdate, nil
+}
@@ -0,0 +1,114 @@
+package campaignupdate
+
+import (
+	"bwastartup/campaign"
+	"bwastartup/policy"
+	"bwastartup/transaction"
+	"bwastartup/user"
+	"errors"
+	"log"
+)
+
+var ErrCampaignNotFound = errors.New("campaign not found")
+
+type Service interface {
+	GetCampaignUpdates(input GetCampaignUpdatesInput, currentUser user.User) ([]CampaignUpdate, error)
+	CreateCampaignUpdate(inputID GetCampaignUpdatesInput, inputData CreateCampaignUpdateInput) (CampaignUpdate, error)
+}
+
+type service struct {
+	repository            Repository
+	campaignRepository    campaign.Repository
+	transactionRepository transaction.Repository
+	notifier              Notifier
+}
+
+func NewService(repository Repository, campaignRepository campaign.Repository, transactionRepository transaction.Repository, notifier Notifier) *service {
+	return &service{repository, campaignRepository, transactionRepository, notifier}
+}
+
+// GetCampaignUpdates includes backers-only posts for the owner, admins and
+// anyone with a paid transaction on the campaign. Drafts and unpublished
+// campaigns are only visible to the owner and admins. currentUser is the
+// zero User for anonymous requests.
+func (s *service) GetCampaignUpdates(input GetCampaignUpdatesInput, currentUser user.User) ([]CampaignUpdate, error) {
+	currentCampaign, err := s.campaignRepository.FindByID(input.ID)
+	if err != nil {
+		return []CampaignUpdate{}, err
+	}
+
+	if currentCampaign.ID == 0 || (!currentCampaign.IsListed() && !policy.CanManage(currentUser, currentCampaign.UserID)) {
+		return []CampaignUpdate{}, ErrCampaignNotFound
+	}
+
+	includeBackersOnly := false
+	if currentUser.ID != 0 {
+		includeBackersOnly = policy.CanManage(currentUser, currentCampaign.UserID)
+
+		if !includeBackersOnly {
+			includeBackersOnly, err = s.transactionRepository.IsBacker(currentCampaign.ID, currentUser.ID)
+			if err != nil {
+				return []CampaignUpdate{}, err
+			}
+		}
+	}
+
+	campaignUpdates, err := s.repository.FindByCampaignID(currentCampaign.ID, includeBackersOnly)
+	if err != nil {
+		return campaignUpdates, err
+	}
+
+	return campaignUpdates, nil
+}
+
+// CreateCampaignUpdate only posts to public campaigns, like comments.
+func (s *service) CreateCampaignUpdate(inputID GetCampaignUpdatesInput, inputData CreateCampaignUpdateInput) (CampaignUpdate, error) {
+	currentCampaign, err := s.campaignRepository.FindByID(inputID.ID)
+	if err != nil {
+		return CampaignUpdate{}, err
+	}
+
+	if currentCampaign.ID == 0 || !currentCampaign.IsListed() {
+		return CampaignUpdate{}, ErrCampaignNotFound
+	}
+
+	if currentCampaign.UserID != inputData.User.ID {
+		return CampaignUpdate{}, policy.ErrNotOwner
+	}
+
+	campaignUpdate := CampaignUpdate{}
+	campaignUpdate.CampaignID = currentCampaign.ID
+	campaignUpdate.UserID = inputData.User.ID
+	campaignUpdate.Title = inputData.Title
+	campaignUpdate.Body = inputData.Body
+	campaignUpdate.BackersOnly = inputData.BackersOnly
+
+	newCampaignUpdate, err := s.repository.Save(campaignUpdate)
+	if err != nil {
+		return newCampaignUpdate, err
+	}
+
+	newCampaignUpdate.User = inputData.User
+
+	// Campaigns can have many backers, so do not keep the owner waiting.
+	go s.notifyBackers(currentCampaign, newCampaignUpdate)
+
+	return newCampaignUpdate, nil
+}
+
+func (s *service) notifyBackers(currentCampaign campaign.Campaign, campaignUpdate CampaignUpdate) {
+	backers, err := s.transactionRepository.GetBackers(currentCampaign.ID)
+	if err != nil {
+		log.Println("notify backers:", err.Error())
+		return
+	}
+
+	if len(backers) == 0 {
+		return
+	}
+
+	err = s.notifier.NotifyBackers(currentCampaign, campaignUpdate, backers)
+	if err != nil {
+		log.Println("notify backers:", err.Error())
+	}
+}
@@ -0,0 +1,30 @@
+package comment
+
+import (
+	"bwastartup/user"
+	"time"
+)
+
+const (
+	BadgeOwner  = "owner"
+	BadgeBacker = "backer"
+)
+
+// Comment is either a top-level comment on a campaign or, when ParentID is
+// set, a reply to one. Deleted and hidden comments are kept so their
+// replies stay in place.
+type Comment struct {
+	ID         int
+	CampaignID int  `gorm:"index"`
+	ParentID   *int `gorm:"index"`
+	UserID     int
+	Body       string
+	EditedAt   *time.Time
+	DeletedAt  *time.Time
+	HiddenAt   *time.Time
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+	User       user.User
+	Replies    []Comment `gorm:"foreignKey:ParentID"`
+	Badge      string    `gorm:"-"`
+}
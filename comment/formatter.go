@@ -0,0 +1,56 @@
+package comment
+
+import "time"
+
+type CommentFormatter struct {
+	ID        int                  `json:"id"`
+	ParentID  *int                 `json:"parent_id"`
+	Body      string               `json:"body"`
+	Badge     string               `json:"badge"`
+	IsEdited  bool                 `json:"is_edited"`
+	IsDeleted bool                 `json:"is_deleted"`
+	IsHidden  bool                 `json:"is_hidden"`
+	CreatedAt time.Time            `json:"created_at"`
+	User      CommentUserFormatter `json:"user"`
+	Replies   []CommentFormatter   `json:"replies"`
+}
+
+type CommentUserFormatter struct {
+	ID       int    `json:"id"`
+	Name     string `json:"name"`
+	ImageURL string `json:"image_url"`
+}
+
+func FormatComment(comment Comment) CommentFormatter {
+	formatter := CommentFormatter{}
+	formatter.ID = comment.ID
+	formatter.ParentID = comment.ParentID
+	formatter.Body = comment.Body
+	formatter.Badge = comment.Badge
+	formatter.IsEdited = comment.EditedAt != nil
+	formatter.IsDeleted = comment.DeletedAt != nil
+	formatter.IsHidden = comment.HiddenAt != nil
+	formatter.CreatedAt = comment.CreatedAt
+
+	userFormatter := CommentUserFormatter{}
+	if !formatter.IsDeleted {
+		userFormatter.ID = comment.User.ID
+		userFormatter.Name = comment.User.Name
+		userFormatter.ImageURL = comment.User.AvatarFileName
+	}
+
+	formatter.User = userFormatter
+	formatter.Replies = FormatComments(comment.Replies)
+
+	return formatter
+}
+
+func FormatComments(comments []Comment) []CommentFormatter {
+	formatters := []CommentFormatter{}
+
+	for _, comment := range comments {
+		formatters = append(formatters, FormatComment(comment))
+	}
+
+	return formatters
+}
@@ -0,0 +1,24 @@
+package comment
+
+import "bwastartup/user"
+
+type GetCommentsInput struct {
+	ID    int `uri:"id" binding:"required"`
+	Page  int `form:"page" binding:"omitempty,min=1"`
+	Limit int `form:"limit" binding:"omitempty,min=1,max=100"`
+}
+
+type GetCommentInput struct {
+	ID int `uri:"id" binding:"required"`
+}
+
+type CreateCommentInput struct {
+	Body     string `json:"body" binding:"required,max=5000"`
+	ParentID int    `json:"parent_id"`
+	User     user.User
+}
+
+type UpdateCommentInput struct {
+	Body string `json:"body" binding:"required,max=5000"`
+	User user.User
+}
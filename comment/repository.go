@@ -0,0 +1,81 @@
+package comment
+
+import (
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type Repository interface {
+	FindByCampaignID(campaignID int, limit int, offset int) ([]Comment, error)
+	CountByCampaignID(campaignID int) (int64, error)
+	FindByID(ID int) (Comment, error)
+	Save(comment Comment) (Comment, error)
+	Update(comment Comment) (Comment, error)
+}
+
+type repository struct {
+	db *gorm.DB
+}
+
+func NewRepository(db *gorm.DB) *repository {
+	return &repository{db}
+}
+
+// FindByCampaignID returns a page of top-level comments, newest first, each
+// with all of its replies, oldest first.
+func (r *repository) FindByCampaignID(campaignID int, limit int, offset int) ([]Comment, error) {
+	var comments []Comment
+
+	err := r.db.Where("campaign_id = ? AND parent_id IS NULL", campaignID).
+		Preload("User").
+		Preload("Replies", func(db *gorm.DB) *gorm.DB {
+			return db.Order("comments.id ASC")
+		}).
+		Preload("Replies.User").
+		Order("id DESC").Limit(limit).Offset(offset).Find(&comments).Error
+	if err != nil {
+		return comments, err
+	}
+
+	return comments, nil
+}
+
+func (r *repository) CountByCampaignID(campaignID int) (int64, error) {
+	var count int64
+
+	err := r.db.Model(&Comment{}).Where("campaign_id = ? AND parent_id IS NULL", campaignID).Count(&count).Error
+	if err != nil {
+		return count, err
+	}
+
+	return count, nil
+}
+
+func (r *repository) FindByID(ID int) (Comment, error) {
+	var comment Comment
+
+	err := r.db.Where("id = ?", ID).Preload("User").Find(&comment).Error
+	if err != nil {
+		return comment, err
+	}
+
+	return comment, nil
+}
+
+func (r *repository) Save(comment Comment) (Comment, error) {
+	err := r.db.Create(&comment).Error
+	if err != nil {
+		return comment, err
+	}
+
+	return comment, nil
+}
+
+func (r *repository) Update(comment Comment) (Comment, error) {
+	err := r.db.Omit(clause.Associations).Save(&comment).Error
+	if err != nil {
+		return comment, err
+	}
+
+	return comment, nil
+}
@@ -0,0 +1,270 @@
+package comment
+
+import (
+	"bwastartup/campaign"
+	"bwastartup/helper"
+	"bwastartup/policy"
+	"bwastartup/transaction"
+	"bwastartup/user"
+	"errors"
+	"time"
+)
+
+const DefaultLimit = 20
+
+var (
+	ErrCampaignNotFound = errors.New("campaign not found")
+	ErrCommentNotFound  = errors.New("comment not found")
+	ErrCommentDeleted   = errors.New("comment has been deleted")
+	ErrNotAuthor        = errors.New("not the author of the comment")
+)
+
+type Service interface {
+	GetComments(input GetCommentsInput, currentUser user.User) ([]Comment, helper.Pagination, error)
+	CreateComment(inputID GetCommentsInput, inputData CreateCommentInput) (Comment, error)
+	UpdateComment(inputID GetCommentInput, inputData UpdateCommentInput) (Comment, error)
+	DeleteComment(input GetCommentInput, currentUser user.User) error
+	HideComment(input GetCommentInput, currentUser user.User, hidden bool) (Comment, error)
+}
+
+type service struct {
+	repository            Repository
+	campaignRepository    campaign.Repository
+	transactionRepository transaction.Repository
+}
+
+func NewService(repository Repository, campaignRepository campaign.Repository, transactionRepository transaction.Repository) *service {
+	return &service{repository, campaignRepository, transactionRepository}
+}
+
+// GetComments pages through the top-level comments of a campaign. The body
+// of deleted comments is always blanked, and the body of hidden ones unless
+// currentUser moderates the campaign.
+func (s *service) GetComments(input GetCommentsInput, currentUser user.User) ([]Comment, helper.Pagination, error) {
+	commentedCampaign, err := s.findCampaign(input.ID)
+	if err != nil {
+		return []Comment{}, helper.Pagination{}, err
+	}
+
+	limit := input.Limit
+	if limit == 0 {
+		limit = DefaultLimit
+	}
+
+	page := input.Page
+	if page == 0 {
+		page = 1
+	}
+
+	total, err := s.repository.CountByCampaignID(commentedCampaign.ID)
+	if err != nil {
+		return []Comment{}, helper.Pagination{}, err
+	}
+
+	comments, err := s.repository.FindByCampaignID(commentedCampaign.ID, limit, (page-1)*limit)
+	if err != nil {
+		return comments, helper.Pagination{}, err
+	}
+
+	pagination := helper.NewPagination(page, limit, total)
+	pagination.HasMore = page < pagination.TotalPages
+
+	badges, err := s.badges(commentedCampaign)
+	if err != nil {
+		return comments, pagination, err
+	}
+
+	canModerate := currentUser.ID != 0 && policy.CanManage(currentUser, commentedCampaign.UserID)
+
+	for i := range comments {
+		comments[i] = prepare(comments[i], badges, canModerate)
+
+		for j := range comments[i].Replies {
+			comments[i].Replies[j] = prepare(comments[i].Replies[j], badges, canModerate)
+		}
+	}
+
+	return comments, pagination, nil
+}
+
+// CreateComment adds a comment, or a reply when ParentID is set. Threads are
+// one level deep, so a reply to a reply is attached to the top-level comment.
+func (s *service) CreateComment(inputID GetCommentsInput, inputData CreateCommentInput) (Comment, error) {
+	commentedCampaign, err := s.findCampaign(inputID.ID)
+	if err != nil {
+		return Comment{}, err
+	}
+
+	comment := Comment{}
+	comment.CampaignID = commentedCampaign.ID
+	comment.UserID = inputData.User.ID
+	comment.Body = inputData.Body
+
+	if inputData.ParentID != 0 {
+		parent, err := s.repository.FindByID(inputData.ParentID)
+		if err != nil {
+			return Comment{}, err
+		}
+
+		if parent.ID == 0 || parent.CampaignID != commentedCampaign.ID {
+			return Comment{}, ErrCommentNotFound
+		}
+
+		parentID := parent.ID
+		if parent.ParentID != nil {
+			parentID = *parent.ParentID
+		}
+		comment.ParentID = &parentID
+	}
+
+	newComment, err := s.repository.Save(comment)
+	if err != nil {
+		return newComment, err
+	}
+
+	newComment.User = inputData.User
+
+	badges, err := s.badges(commentedCampaign)
+	if err != nil {
+		return newComment, err
+	}
+	newComment.Badge = badges[newComment.UserID]
+
+	return newComment, nil
+}
+
+func (s *service) UpdateComment(inputID GetCommentInput, inputData UpdateCommentInput) (Comment, error) {
+	comment, err := s.findComment(inputID.ID)
+	if err != nil {
+		return comment, err
+	}
+
+	if comment.UserID != inputData.User.ID {
+		return comment, ErrNotAuthor
+	}
+
+	now := time.Now()
+	comment.Body = inputData.Body
+	comment.EditedAt = &now
+
+	updatedComment, err := s.repository.Update(comment)
+	if err != nil {
+		return updatedComment, err
+	}
+
+	return updatedComment, nil
+}
+
+// DeleteComment can be done by the author or an admin. The comment stays as
+// a placeholder so its replies keep their thread.
+func (s *service) DeleteComment(input GetCommentInput, currentUser user.User) error {
+	comment, err := s.findComment(input.ID)
+	if err != nil {
+		return err
+	}
+
+	if !policy.CanManage(currentUser, comment.UserID) {
+		return ErrNotAuthor
+	}
+
+	now := time.Now()
+	comment.DeletedAt = &now
+
+	_, err = s.repository.Update(comment)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// HideComment is the moderation tool of campaign owners and admins. Only
+// they can still read the body of a hidden comment.
+func (s *service) HideComment(input GetCommentInput, currentUser user.User, hidden bool) (Comment, error) {
+	comment, err := s.findComment(input.ID)
+	if err != nil {
+		return comment, err
+	}
+
+	commentedCampaign, err := s.campaignRepository.FindByID(comment.CampaignID)
+	if err != nil {
+		return comment, err
+	}
+
+	if !policy.CanManage(currentUser, commentedCampaign.UserID) {
+		return comment, policy.ErrNotOwner
+	}
+
+	comment.HiddenAt = nil
+	if hidden {
+		now := time.Now()
+		comment.HiddenAt = &now
+	}
+
+	updatedComment, err := s.repository.Update(comment)
+	if err != nil {
+		return updatedComment, err
+	}
+
+	return updatedComment, nil
+}
+
+func (s *service) findCampaign(campaignID int) (campaign.Campaign, error) {
+	commentedCampaign, err := s.campaignRepository.FindByID(campaignID)
+	if err != nil {
+		return commentedCampaign, err
+	}
+
+	if commentedCampaign.ID == 0 || !commentedCampaign.IsListed() {
+		return commentedCampaign, ErrCampaignNotFound
+	}
+
+	return commentedCampaign, nil
+}
+
+func (s *service) findComment(commentID int) (Comment, error) {
+	comment, err := s.repository.FindByID(commentID)
+	if err != nil {
+		return comment, err
+	}
+
+	if comment.ID == 0 {
+		return comment, ErrCommentNotFound
+	}
+
+	if comment.DeletedAt != nil {
+		return comment, ErrCommentDeleted
+	}
+
+	return comment, nil
+}
+
+// badges maps the owner and every paying backer of the campaign to the badge
+// shown next to their comments.
+func (s *service) badges(commentedCampaign campaign.Campaign) (map[int]string, error) {
+	transactions, err := s.transactionRepository.GetByCampaignID(commentedCampaign.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	badges := map[int]string{}
+	for _, pledge := range transactions {
+		if pledge.Status == transaction.StatusPaid {
+			badges[pledge.UserID] = BadgeBacker
+		}
+	}
+
+	badges[commentedCampaign.UserID] = BadgeOwner
+
+	return badges, nil
+}
+
+func prepare(comment Comment, badges map[int]string, canModerate bool) Comment {
+	comment.Badge = badges[comment.UserID]
+
+	if comment.DeletedAt != nil || (comment.HiddenAt != nil && !canModerate) {
+		comment.Body = ""
+	}
+
+	return comment
+}
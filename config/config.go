@@ -0,0 +1,43 @@
+package config
+
+import (
+	"os"
+	"strconv"
+	"time"
+)
+
+func Get(key string, fallback string) string {
+	value, ok := os.LookupEnv(key)
+	if !ok || value == "" {
+		return fallback
+	}
+
+	return value
+}
+
+func Int(key string, fallback int) int {
+	value, err := strconv.Atoi(Get(key, ""))
+	if err != nil {
+		return fallback
+	}
+
+	return value
+}
+
+func Bool(key string, fallback bool) bool {
+	value, err := strconv.ParseBool(Get(key, ""))
+	if err != nil {
+		return fallback
+	}
+
+	return value
+}
+
+func Duration(key string, fallback time.Duration) time.Duration {
+	value, err := time.ParseDuration(Get(key, ""))
+	if err != nil {
+		return fallback
+	}
+
+	return value
+}
@@ -0,0 +1,23 @@
+package handler
+
+import (
+	"bwastartup/auth"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type authHandler struct {
+	service auth.Service
+}
+
+func NewAuthHandler(service auth.Service) *authHandler {
+	return &authHandler{service}
+}
+
+// JWKS is served as a bare JSON Web Key Set rather than wrapped in
+// helper.APIResponse, because JWT libraries expect the standard document.
+func (h *authHandler) JWKS(c *gin.Context) {
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(http.StatusOK, h.service.JWKS())
+}
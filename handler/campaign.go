@@ -3,33 +3,68 @@ package handler
 import (
 	"bwastartup/campaign"
 	"bwastartup/helper"
+	"bwastartup/imaging"
+	"bwastartup/storage"
 	"bwastartup/user"
 	"fmt"
+	"log"
 	"net/http"
-	"strconv"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 )
 
 type campaignHandler struct {
-	service campaign.Service
+	service       campaign.Service
+	storage       storage.Storage
+	imagePipeline *imaging.Pipeline
 }
 
-func NewCampaignHandler(service campaign.Service) *campaignHandler {
-	return &campaignHandler{service}
+func NewCampaignHandler(service campaign.Service, storage storage.Storage, imagePipeline *imaging.Pipeline) *campaignHandler {
+	return &campaignHandler{service, storage, imagePipeline}
 }
 
 func (h *campaignHandler) GetCampaigns(c *gin.Context) {
-	userID, _ := strconv.Atoi(c.Query("user_id"))
+	var input campaign.GetCampaignsInput
 
-	campaigns, err := h.service.GetCampaigns(userID)
+	err := c.ShouldBindQuery(&input)
+	if err != nil {
+		errorMessage := gin.H{"errors": err.Error()}
+		response := helper.APIResponse("Error to Get Campaigns", http.StatusUnprocessableEntity, "error", errorMessage)
+		c.JSON(http.StatusUnprocessableEntity, response)
+		return
+	}
+
+	campaigns, pagination, err := h.service.GetCampaigns(input)
 	if err != nil {
 		response := helper.APIResponse("Error to Get Campaigns", http.StatusBadRequest, "error", nil)
 		c.JSON(http.StatusBadRequest, response)
 		return
 	}
 
-	response := helper.APIResponse("Lists of Campaigns", http.StatusOK, "success", campaign.FormatCampaigns(campaigns))
+	response := helper.PaginatedAPIResponse("Lists of Campaigns", http.StatusOK, "success", campaign.FormatCampaigns(campaigns), pagination)
+	c.JSON(http.StatusOK, response)
+}
+
+func (h *campaignHandler) SearchCampaigns(c *gin.Context) {
+	var input campaign.SearchCampaignsInput
+
+	err := c.ShouldBindQuery(&input)
+	if err != nil {
+		errorMessage := gin.H{"errors": err.Error()}
+		response := helper.APIResponse("Failed to Search Campaigns", http.StatusUnprocessableEntity, "error", errorMessage)
+		c.JSON(http.StatusUnprocessableEntity, response)
+		return
+	}
+
+	results, pagination, err := h.service.SearchCampaigns(input)
+	if err != nil {
+		response := helper.APIResponse("Failed to Search Campaigns", http.StatusBadRequest, "error", nil)
+		c.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	response := helper.PaginatedAPIResponse("Search Results of Campaigns", http.StatusOK, "success", campaign.FormatSearchResults(results), pagination)
 	c.JSON(http.StatusOK, response)
 }
 
@@ -49,6 +84,10 @@ func (h *campaignHandler) GetCampaign(c *gin.Context) {
 		return
 	}
 
+	if currentUser, ok := c.Get("currentUser"); ok {
+		input.User = currentUser.(user.User)
+	}
+
 	campaignDetail, err := h.service.GetCampaignByID(input)
 	if err != nil {
 		response := helper.APIResponse("Failed to Get Detail of Campaign", http.StatusBadRequest, "error", nil)
@@ -60,6 +99,39 @@ func (h *campaignHandler) GetCampaign(c *gin.Context) {
 	c.JSON(http.StatusOK, response)
 }
 
+// GetCampaignBySlug answers a slug the campaign had before a rename with a
+// permanent redirect to its current slug.
+func (h *campaignHandler) GetCampaignBySlug(c *gin.Context) {
+	var input campaign.GetCampaignBySlugInput
+
+	err := c.ShouldBindUri(&input)
+	if err != nil {
+		response := helper.APIResponse("Failed to Get Detail Campaign", http.StatusBadRequest, "error", nil)
+		c.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	if currentUser, ok := c.Get("currentUser"); ok {
+		input.User = currentUser.(user.User)
+	}
+
+	campaignDetail, moved, err := h.service.GetCampaignBySlug(input)
+	if err != nil {
+		response := helper.APIResponse("Failed to Get Detail of Campaign", http.StatusNotFound, "error", nil)
+		c.JSON(http.StatusNotFound, response)
+		return
+	}
+
+	if moved {
+		location := strings.TrimSuffix(c.Request.URL.Path, input.Slug) + campaignDetail.Slug
+		c.Redirect(http.StatusMovedPermanently, location)
+		return
+	}
+
+	response := helper.APIResponse("Detail of Campaign", http.StatusOK, "success", campaign.FormatCampaignDetail(campaignDetail))
+	c.JSON(http.StatusOK, response)
+}
+
 func (h *campaignHandler) CreateCampaign(c *gin.Context) {
 	var input campaign.CreateCampaignInput
 	err := c.ShouldBindJSON(&input)
@@ -76,6 +148,13 @@ func (h *campaignHandler) CreateCampaign(c *gin.Context) {
 	input.User = currentUser
 
 	newCampaign, err := h.service.CreateCampaign(input)
+	if err == campaign.ErrDeadlineInPast {
+		errorMessage := gin.H{"errors": err.Error()}
+		response := helper.APIResponse("Create Campaign Failed", http.StatusUnprocessableEntity, "error", errorMessage)
+		c.JSON(http.StatusUnprocessableEntity, response)
+		return
+	}
+
 	if err != nil {
 		response := helper.APIResponse("Create Campaign Failed", http.StatusBadRequest, "error", nil)
 		c.JSON(http.StatusBadRequest, response)
@@ -111,6 +190,13 @@ func (h *campaignHandler) UpdateCampaign(c *gin.Context) {
 	inputData.User = currentUser
 
 	updatedCampaign, err := h.service.UpdateCampaign(inputID, inputData)
+	if err == campaign.ErrDeadlineInPast || err == campaign.ErrDeadlineLocked {
+		errorMessage := gin.H{"errors": err.Error()}
+		response := helper.APIResponse("Update Campaign Failed", http.StatusUnprocessableEntity, "error", errorMessage)
+		c.JSON(http.StatusUnprocessableEntity, response)
+		return
+	}
+
 	if err != nil {
 		response := helper.APIResponse("Update Campaign Failed", http.StatusBadRequest, "error", nil)
 		c.JSON(http.StatusUnprocessableEntity, response)
@@ -146,21 +232,40 @@ func (h *campaignHandler) UploadImage(c *gin.Context) {
 		return
 	}
 
-	userID := currentUser.ID
-	path := fmt.Sprintf("/home/muhammadfahrezam/bwastartup/images/%d-%s", userID, file.Filename)
-	pathName := fmt.Sprintf("%d-%s", userID, file.Filename)
+	err = h.service.AuthorizeImageUpload(input)
+	if err != nil {
+		data := gin.H{"is_uploaded": false, "errors": err.Error()}
+		response := helper.APIResponse("Upload Campaign Image Failed", http.StatusBadRequest, "error", data)
+
+		c.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	keyPrefix := fmt.Sprintf("campaign-images/%d", input.CampaignID)
 
-	err = c.SaveUploadedFile(file, path)
+	imageURLs, err := uploadImage(c.Request.Context(), h.imagePipeline, h.storage, file, keyPrefix)
 	if err != nil {
 		data := gin.H{"is_uploaded": false}
+		if isImageError(err) {
+			data["errors"] = err.Error()
+		}
+
 		response := helper.APIResponse("Upload Campaign image failed", http.StatusBadRequest, "error", data)
 
 		c.JSON(http.StatusBadRequest, response)
 		return
 	}
 
-	_, err = h.service.SaveCampaignImage(input, pathName)
+	files := campaign.CampaignImageFiles{
+		Full:      imageURLs["full"],
+		Card:      imageURLs["card"],
+		Thumbnail: imageURLs["thumbnail"],
+	}
+
+	_, err = h.service.SaveCampaignImage(input, files)
 	if err != nil {
+		deleteImages(c.Request.Context(), h.storage, imageURLs)
+
 		data := gin.H{"is_uploaded": false}
 		response := helper.APIResponse("Upload Campaign Image Failed", http.StatusBadRequest, "error", data)
 
@@ -173,3 +278,292 @@ func (h *campaignHandler) UploadImage(c *gin.Context) {
 
 	c.JSON(http.StatusOK, response)
 }
+
+func (h *campaignHandler) DeleteImage(c *gin.Context) {
+	var input campaign.GetCampaignImageInput
+
+	err := c.ShouldBindUri(&input)
+	if err != nil {
+		response := helper.APIResponse("Delete Campaign Image Failed", http.StatusBadRequest, "error", nil)
+		c.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	input.User = c.MustGet("currentUser").(user.User)
+
+	deletedImage, err := h.service.DeleteCampaignImage(input)
+	if err != nil {
+		errorMessage := gin.H{"errors": err.Error()}
+		response := helper.APIResponse("Delete Campaign Image Failed", http.StatusBadRequest, "error", errorMessage)
+		c.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	// The image is already gone from the campaign, so a file that cannot be
+	// deleted is only logged.
+	fileURLs := map[string]bool{deletedImage.FileName: true, deletedImage.CardFileName: true, deletedImage.ThumbnailFileName: true}
+	for fileURL := range fileURLs {
+		key, ok := storage.KeyFromURL(h.storage, fileURL)
+		if !ok {
+			continue
+		}
+
+		err := h.storage.Delete(c.Request.Context(), key)
+		if err != nil {
+			log.Println("delete campaign image file:", err.Error())
+		}
+	}
+
+	response := helper.APIResponse("Delete Campaign Image Success", http.StatusOK, "success", nil)
+	c.JSON(http.StatusOK, response)
+}
+
+func (h *campaignHandler) SetPrimaryImage(c *gin.Context) {
+	var input campaign.GetCampaignImageInput
+
+	err := c.ShouldBindUri(&input)
+	if err != nil {
+		response := helper.APIResponse("Set Primary Image Failed", http.StatusBadRequest, "error", nil)
+		c.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	input.User = c.MustGet("currentUser").(user.User)
+
+	primaryImage, err := h.service.SetPrimaryCampaignImage(input)
+	if err != nil {
+		errorMessage := gin.H{"errors": err.Error()}
+		response := helper.APIResponse("Set Primary Image Failed", http.StatusBadRequest, "error", errorMessage)
+		c.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	response := helper.APIResponse("Set Primary Image Success", http.StatusOK, "success", campaign.FormatCampaignImage(primaryImage))
+	c.JSON(http.StatusOK, response)
+}
+
+func (h *campaignHandler) ReorderImages(c *gin.Context) {
+	var inputID campaign.GetCampaignDetailInput
+
+	err := c.ShouldBindUri(&inputID)
+	if err != nil {
+		response := helper.APIResponse("Reorder Campaign Images Failed", http.StatusBadRequest, "error", nil)
+		c.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	var inputData campaign.ReorderCampaignImagesInput
+	err = c.ShouldBindJSON(&inputData)
+	if err != nil {
+		errors := helper.FormatValidationError(err)
+		errorMessage := gin.H{"errors": errors}
+
+		response := helper.APIResponse("Reorder Campaign Images Failed", http.StatusUnprocessableEntity, "error", errorMessage)
+		c.JSON(http.StatusUnprocessableEntity, response)
+		return
+	}
+
+	inputData.User = c.MustGet("currentUser").(user.User)
+
+	campaignImages, err := h.service.ReorderCampaignImages(inputID, inputData)
+	if err != nil {
+		errorMessage := gin.H{"errors": err.Error()}
+		response := helper.APIResponse("Reorder Campaign Images Failed", http.StatusBadRequest, "error", errorMessage)
+		c.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	response := helper.APIResponse("Reorder Campaign Images Success", http.StatusOK, "success", campaign.FormatCampaignImages(campaignImages))
+	c.JSON(http.StatusOK, response)
+}
+
+func (h *campaignHandler) UnpublishCampaign(c *gin.Context) {
+	var input campaign.GetCampaignDetailInput
+
+	err := c.ShouldBindUri(&input)
+	if err != nil {
+		response := helper.APIResponse("Failed to Unpublish Campaign", http.StatusBadRequest, "error", nil)
+		c.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	currentUser := c.MustGet("currentUser").(user.User)
+
+	unpublishedCampaign, err := h.service.UnpublishCampaign(input, currentUser)
+	if err != nil {
+		errorMessage := gin.H{"errors": err.Error()}
+		response := helper.APIResponse("Failed to Unpublish Campaign", http.StatusBadRequest, "error", errorMessage)
+		c.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	response := helper.APIResponse("Campaign has been Unpublished", http.StatusOK, "success", campaign.FormatCampaign(unpublishedCampaign))
+	c.JSON(http.StatusOK, response)
+}
+
+func (h *campaignHandler) PublishCampaign(c *gin.Context) {
+	var input campaign.ChangeCampaignStatusInput
+
+	err := c.ShouldBindUri(&input)
+	if err != nil {
+		response := helper.APIResponse("Failed to Publish Campaign", http.StatusBadRequest, "error", nil)
+		c.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	input.User = c.MustGet("currentUser").(user.User)
+
+	publishedCampaign, err := h.service.PublishCampaign(input)
+	if err != nil {
+		errorMessage := gin.H{"errors": err.Error()}
+		response := helper.APIResponse("Failed to Publish Campaign", http.StatusBadRequest, "error", errorMessage)
+		c.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	response := helper.APIResponse("Campaign has been Published", http.StatusOK, "success", campaign.FormatCampaign(publishedCampaign))
+	c.JSON(http.StatusOK, response)
+}
+
+func (h *campaignHandler) CloseCampaign(c *gin.Context) {
+	var input campaign.ChangeCampaignStatusInput
+
+	err := c.ShouldBindUri(&input)
+	if err != nil {
+		response := helper.APIResponse("Failed to Close Campaign", http.StatusBadRequest, "error", nil)
+		c.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	input.User = c.MustGet("currentUser").(user.User)
+
+	closedCampaign, err := h.service.CloseCampaign(input)
+	if err != nil {
+		errorMessage := gin.H{"errors": err.Error()}
+		response := helper.APIResponse("Failed to Close Campaign", http.StatusBadRequest, "error", errorMessage)
+		c.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	response := helper.APIResponse("Campaign has been Closed", http.StatusOK, "success", campaign.FormatCampaign(closedCampaign))
+	c.JSON(http.StatusOK, response)
+}
+
+func (h *campaignHandler) GetRewards(c *gin.Context) {
+	var input campaign.GetCampaignDetailInput
+
+	err := c.ShouldBindUri(&input)
+	if err != nil {
+		response := helper.APIResponse("Failed to Get Rewards", http.StatusBadRequest, "error", nil)
+		c.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	if currentUser, ok := c.Get("currentUser"); ok {
+		input.User = currentUser.(user.User)
+	}
+
+	rewards, err := h.service.GetRewards(input)
+	if err != nil {
+		response := helper.APIResponse("Failed to Get Rewards", http.StatusBadRequest, "error", nil)
+		c.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	response := helper.APIResponse("List of Rewards", http.StatusOK, "success", campaign.FormatRewards(rewards))
+	c.JSON(http.StatusOK, response)
+}
+
+func (h *campaignHandler) CreateReward(c *gin.Context) {
+	var inputID campaign.GetCampaignDetailInput
+
+	err := c.ShouldBindUri(&inputID)
+	if err != nil {
+		response := helper.APIResponse("Create Reward Failed", http.StatusBadRequest, "error", nil)
+		c.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	var inputData campaign.RewardInput
+	err = c.ShouldBindJSON(&inputData)
+	if err != nil {
+		errors := helper.FormatValidationError(err)
+		errorMessage := gin.H{"errors": errors}
+
+		response := helper.APIResponse("Create Reward Failed", http.StatusUnprocessableEntity, "error", errorMessage)
+		c.JSON(http.StatusUnprocessableEntity, response)
+		return
+	}
+
+	inputData.User = c.MustGet("currentUser").(user.User)
+
+	newReward, err := h.service.CreateReward(inputID, inputData)
+	if err != nil {
+		errorMessage := gin.H{"errors": err.Error()}
+		response := helper.APIResponse("Create Reward Failed", http.StatusBadRequest, "error", errorMessage)
+		c.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	response := helper.APIResponse("Create Reward Success", http.StatusOK, "success", campaign.FormatReward(newReward))
+	c.JSON(http.StatusOK, response)
+}
+
+func (h *campaignHandler) UpdateReward(c *gin.Context) {
+	var inputID campaign.GetRewardInput
+
+	err := c.ShouldBindUri(&inputID)
+	if err != nil {
+		response := helper.APIResponse("Update Reward Failed", http.StatusBadRequest, "error", nil)
+		c.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	var inputData campaign.RewardInput
+	err = c.ShouldBindJSON(&inputData)
+	if err != nil {
+		errors := helper.FormatValidationError(err)
+		errorMessage := gin.H{"errors": errors}
+
+		response := helper.APIResponse("Update Reward Failed", http.StatusUnprocessableEntity, "error", errorMessage)
+		c.JSON(http.StatusUnprocessableEntity, response)
+		return
+	}
+
+	inputData.User = c.MustGet("currentUser").(user.User)
+
+	updatedReward, err := h.service.UpdateReward(inputID, inputData)
+	if err != nil {
+		errorMessage := gin.H{"errors": err.Error()}
+		response := helper.APIResponse("Update Reward Failed", http.StatusBadRequest, "error", errorMessage)
+		c.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	response := helper.APIResponse("Update Reward Success", http.StatusOK, "success", campaign.FormatReward(updatedReward))
+	c.JSON(http.StatusOK, response)
+}
+
+func (h *campaignHandler) DeleteReward(c *gin.Context) {
+	var input campaign.GetRewardInput
+
+	err := c.ShouldBindUri(&input)
+	if err != nil {
+		response := helper.APIResponse("Delete Reward Failed", http.StatusBadRequest, "error", nil)
+		c.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	input.User = c.MustGet("currentUser").(user.User)
+
+	err = h.service.DeleteReward(input)
+	if err != nil {
+		errorMessage := gin.H{"errors": err.Error()}
+		response := helper.APIResponse("Delete Reward Failed", http.StatusBadRequest, "error", errorMessage)
+		c.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	response := helper.APIResponse("Delete Reward Success", http.StatusOK, "success", nil)
+	c.JSON(http.StatusOK, response)
+}
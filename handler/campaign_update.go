@@ -0,0 +1,80 @@
+package handler
+
+import (
+	"bwastartup/campaignupdate"
+	"bwastartup/helper"
+	"bwastartup/user"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type campaignUpdateHandler struct {
+	service campaignupdate.Service
+}
+
+func NewCampaignUpdateHandler(service campaignupdate.Service) *campaignUpdateHandler {
+	return &campaignUpdateHandler{service}
+}
+
+func (h *campaignUpdateHandler) GetCampaignUpdates(c *gin.Context) {
+	var input campaignupdate.GetCampaignUpdatesInput
+
+	err := c.ShouldBindUri(&input)
+	if err != nil {
+		response := helper.APIResponse("Failed to Get Campaign Updates", http.StatusBadRequest, "error", nil)
+		c.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	// The route is public, currentUser is only set for signed in requests.
+	currentUser := user.User{}
+	if value, ok := c.Get("currentUser"); ok {
+		currentUser = value.(user.User)
+	}
+
+	campaignUpdates, err := h.service.GetCampaignUpdates(input, currentUser)
+	if err != nil {
+		response := helper.APIResponse("Failed to Get Campaign Updates", http.StatusBadRequest, "error", nil)
+		c.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	response := helper.APIResponse("List of Campaign Updates", http.StatusOK, "success", campaignupdate.FormatCampaignUpdates(campaignUpdates))
+	c.JSON(http.StatusOK, response)
+}
+
+func (h *campaignUpdateHandler) CreateCampaignUpdate(c *gin.Context) {
+	var inputID campaignupdate.GetCampaignUpdatesInput
+
+	err := c.ShouldBindUri(&inputID)
+	if err != nil {
+		response := helper.APIResponse("Create Campaign Update Failed", http.StatusBadRequest, "error", nil)
+		c.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	var inputData campaignupdate.CreateCampaignUpdateInput
+	err = c.ShouldBindJSON(&inputData)
+	if err != nil {
+		errors := helper.FormatValidationError(err)
+		errorMessage := gin.H{"errors": errors}
+
+		response := helper.APIResponse("Create Campaign Update Failed", http.StatusUnprocessableEntity, "error", errorMessage)
+		c.JSON(http.StatusUnprocessableEntity, response)
+		return
+	}
+
+	inputData.User = c.MustGet("currentUser").(user.User)
+
+	newCampaignUpdate, err := h.service.CreateCampaignUpdate(inputID, inputData)
+	if err != nil {
+		errorMessage := gin.H{"errors": err.Error()}
+		response := helper.APIResponse("Create Campaign Update Failed", http.StatusBadRequest, "error", errorMessage)
+		c.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	response := helper.APIResponse("Create Campaign Update Success", http.StatusOK, "success", campaignupdate.FormatCampaignUpdate(newCampaignUpdate))
+	c.JSON(http.StatusOK, response)
+}
@@ -0,0 +1,178 @@
+package handler
+
+import (
+	"bwastartup/comment"
+	"bwastartup/helper"
+	"bwastartup/user"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type commentHandler struct {
+	service comment.Service
+}
+
+func NewCommentHandler(service comment.Service) *commentHandler {
+	return &commentHandler{service}
+}
+
+func (h *commentHandler) GetComments(c *gin.Context) {
+	var input comment.GetCommentsInput
+
+	err := c.ShouldBindUri(&input)
+	if err != nil {
+		response := helper.APIResponse("Failed to Get Comments", http.StatusBadRequest, "error", nil)
+		c.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	err = c.ShouldBindQuery(&input)
+	if err != nil {
+		errorMessage := gin.H{"errors": err.Error()}
+		response := helper.APIResponse("Failed to Get Comments", http.StatusUnprocessableEntity, "error", errorMessage)
+		c.JSON(http.StatusUnprocessableEntity, response)
+		return
+	}
+
+	currentUser := user.User{}
+	if value, ok := c.Get("currentUser"); ok {
+		currentUser = value.(user.User)
+	}
+
+	comments, pagination, err := h.service.GetComments(input, currentUser)
+	if err != nil {
+		response := helper.APIResponse("Failed to Get Comments", http.StatusBadRequest, "error", nil)
+		c.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	response := helper.PaginatedAPIResponse("List of Comments", http.StatusOK, "success", comment.FormatComments(comments), pagination)
+	c.JSON(http.StatusOK, response)
+}
+
+func (h *commentHandler) CreateComment(c *gin.Context) {
+	var inputID comment.GetCommentsInput
+
+	err := c.ShouldBindUri(&inputID)
+	if err != nil {
+		response := helper.APIResponse("Create Comment Failed", http.StatusBadRequest, "error", nil)
+		c.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	var inputData comment.CreateCommentInput
+	err = c.ShouldBindJSON(&inputData)
+	if err != nil {
+		errors := helper.FormatValidationError(err)
+		errorMessage := gin.H{"errors": errors}
+
+		response := helper.APIResponse("Create Comment Failed", http.StatusUnprocessableEntity, "error", errorMessage)
+		c.JSON(http.StatusUnprocessableEntity, response)
+		return
+	}
+
+	inputData.User = c.MustGet("currentUser").(user.User)
+
+	newComment, err := h.service.CreateComment(inputID, inputData)
+	if err != nil {
+		errorMessage := gin.H{"errors": err.Error()}
+		response := helper.APIResponse("Create Comment Failed", http.StatusBadRequest, "error", errorMessage)
+		c.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	response := helper.APIResponse("Create Comment Success", http.StatusOK, "success", comment.FormatComment(newComment))
+	c.JSON(http.StatusOK, response)
+}
+
+func (h *commentHandler) UpdateComment(c *gin.Context) {
+	var inputID comment.GetCommentInput
+
+	err := c.ShouldBindUri(&inputID)
+	if err != nil {
+		response := helper.APIResponse("Update Comment Failed", http.StatusBadRequest, "error", nil)
+		c.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	var inputData comment.UpdateCommentInput
+	err = c.ShouldBindJSON(&inputData)
+	if err != nil {
+		errors := helper.FormatValidationError(err)
+		errorMessage := gin.H{"errors": errors}
+
+		response := helper.APIResponse("Update Comment Failed", http.StatusUnprocessableEntity, "error", errorMessage)
+		c.JSON(http.StatusUnprocessableEntity, response)
+		return
+	}
+
+	inputData.User = c.MustGet("currentUser").(user.User)
+
+	updatedComment, err := h.service.UpdateComment(inputID, inputData)
+	if err != nil {
+		errorMessage := gin.H{"errors": err.Error()}
+		response := helper.APIResponse("Update Comment Failed", http.StatusBadRequest, "error", errorMessage)
+		c.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	response := helper.APIResponse("Update Comment Success", http.StatusOK, "success", comment.FormatComment(updatedComment))
+	c.JSON(http.StatusOK, response)
+}
+
+func (h *commentHandler) DeleteComment(c *gin.Context) {
+	var input comment.GetCommentInput
+
+	err := c.ShouldBindUri(&input)
+	if err != nil {
+		response := helper.APIResponse("Delete Comment Failed", http.StatusBadRequest, "error", nil)
+		c.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	currentUser := c.MustGet("currentUser").(user.User)
+
+	err = h.service.DeleteComment(input, currentUser)
+	if err != nil {
+		errorMessage := gin.H{"errors": err.Error()}
+		response := helper.APIResponse("Delete Comment Failed", http.StatusBadRequest, "error", errorMessage)
+		c.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	response := helper.APIResponse("Delete Comment Success", http.StatusOK, "success", nil)
+	c.JSON(http.StatusOK, response)
+}
+
+func (h *commentHandler) HideComment(c *gin.Context) {
+	h.setHidden(c, true)
+}
+
+func (h *commentHandler) UnhideComment(c *gin.Context) {
+	h.setHidden(c, false)
+}
+
+func (h *commentHandler) setHidden(c *gin.Context, hidden bool) {
+	var input comment.GetCommentInput
+
+	err := c.ShouldBindUri(&input)
+	if err != nil {
+		response := helper.APIResponse("Failed to Moderate Comment", http.StatusBadRequest, "error", nil)
+		c.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	currentUser := c.MustGet("currentUser").(user.User)
+
+	moderatedComment, err := h.service.HideComment(input, currentUser, hidden)
+	if err != nil {
+		errorMessage := gin.H{"errors": err.Error()}
+		response := helper.APIResponse("Failed to Moderate Comment", http.StatusBadRequest, "error", errorMessage)
+		c.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	response := helper.APIResponse("Comment has been Moderated", http.StatusOK, "success", comment.FormatComment(moderatedComment))
+	c.JSON(http.StatusOK, response)
+}
@@ -0,0 +1,62 @@
+package handler
+
+import (
+	"bwastartup/payment"
+	"bytes"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// fakePaymentHandler serves the checkout page of the fake payment provider.
+// It is only routed when that provider is selected.
+type fakePaymentHandler struct {
+	checkout payment.Checkout
+}
+
+func NewFakePaymentHandler(checkout payment.Checkout) *fakePaymentHandler {
+	return &fakePaymentHandler{checkout}
+}
+
+func (h *fakePaymentHandler) Checkout(c *gin.Context) {
+	var input payment.FakeCheckoutInput
+
+	err := c.ShouldBindQuery(&input)
+	if err != nil {
+		c.String(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	var page bytes.Buffer
+
+	err = h.checkout.CheckoutPage(&page, input)
+	if err != nil {
+		c.String(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	c.Data(http.StatusOK, "text/html; charset=utf-8", page.Bytes())
+}
+
+func (h *fakePaymentHandler) Complete(c *gin.Context) {
+	var input payment.FakeCheckoutInput
+
+	err := c.ShouldBind(&input)
+	if err != nil {
+		c.String(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	err = h.checkout.Complete(input)
+	if err == payment.ErrInvalidSignature || err == payment.ErrInvalidPayload {
+		c.String(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err != nil {
+		c.String(http.StatusBadGateway, "payment notification failed: "+err.Error())
+		return
+	}
+
+	c.String(http.StatusOK, "payment "+input.Status)
+}
@@ -4,6 +4,7 @@ import (
 	"bwastartup/helper"
 	"bwastartup/transaction"
 	"bwastartup/user"
+	"log"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
@@ -17,6 +18,20 @@ func NewTransactionHandler(service transaction.Service) *transactionHandler {
 	return &transactionHandler{service}
 }
 
+func (h *transactionHandler) GetTransactions(c *gin.Context) {
+	currentUser := c.MustGet("currentUser").(user.User)
+
+	transactions, err := h.service.GetTransactions(currentUser)
+	if err != nil {
+		response := helper.APIResponse("Failed to Get Transactions", http.StatusBadRequest, "error", nil)
+		c.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	response := helper.APIResponse("List of Transactions", http.StatusOK, "success", transaction.FormatAdminTransactions(transactions))
+	c.JSON(http.StatusOK, response)
+}
+
 func (h *transactionHandler) GetCampaignTransaction(c *gin.Context) {
 	var input transaction.GetCampaignTransactionsInput
 
@@ -56,6 +71,37 @@ func (h *transactionHandler) GetUserTransactions(c *gin.Context) {
 	c.JSON(http.StatusOK, response)
 }
 
+func (h *transactionHandler) GetTransactionByCode(c *gin.Context) {
+	var input transaction.GetTransactionByCodeInput
+
+	err := c.ShouldBindUri(&input)
+	if err != nil {
+		response := helper.APIResponse("Failed to Get Transaction", http.StatusBadRequest, "error", nil)
+		c.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	input.User = c.MustGet("currentUser").(user.User)
+
+	transactionDetail, err := h.service.GetTransactionByCode(input)
+	if err == transaction.ErrInvalidCode || err == transaction.ErrTransactionNotFound {
+		errorMessage := gin.H{"errors": err.Error()}
+		response := helper.APIResponse("Failed to Get Transaction", http.StatusNotFound, "error", errorMessage)
+		c.JSON(http.StatusNotFound, response)
+		return
+	}
+
+	if err != nil {
+		errorMessage := gin.H{"errors": err.Error()}
+		response := helper.APIResponse("Failed to Get Transaction", http.StatusBadRequest, "error", errorMessage)
+		c.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	response := helper.APIResponse("Detail of Transaction", http.StatusOK, "success", transaction.FormatTransactionDetail(transactionDetail))
+	c.JSON(http.StatusOK, response)
+}
+
 func (h *transactionHandler) CreateTransaction(c *gin.Context) {
 	var input transaction.CreateTransactionInput
 
@@ -72,6 +118,13 @@ func (h *transactionHandler) CreateTransaction(c *gin.Context) {
 	currentUser := c.MustGet("currentUser").(user.User)
 	input.User = currentUser
 	newTransaction, err := h.service.CreateTransaction(input)
+	if err == transaction.ErrCampaignNotOpen || err == transaction.ErrRewardNotFound || err == transaction.ErrRewardSoldOut || err == transaction.ErrAmountBelowMinimum {
+		errorMessage := gin.H{"errors": err.Error()}
+		response := helper.APIResponse("Create Transaction Failed", http.StatusUnprocessableEntity, "error", errorMessage)
+		c.JSON(http.StatusUnprocessableEntity, response)
+		return
+	}
+
 	if err != nil {
 		response := helper.APIResponse("Create Transaction Failed", http.StatusBadRequest, "error", nil)
 		c.JSON(http.StatusBadRequest, response)
@@ -83,21 +136,30 @@ func (h *transactionHandler) CreateTransaction(c *gin.Context) {
 }
 
 func (h *transactionHandler) GetNotification(c *gin.Context) {
-	var input transaction.TransactionNotificationInput
-
-	err := c.ShouldBindJSON(&input)
+	body, err := c.GetRawData()
 	if err != nil {
+		log.Println("reject payment notification:", err.Error())
+
 		response := helper.APIResponse("Process Notification Failed", http.StatusBadRequest, "error", nil)
 		c.JSON(http.StatusBadRequest, response)
 		return
 	}
 
+	input := transaction.TransactionNotificationInput{
+		Header: c.Request.Header,
+		Body:   body,
+	}
+
 	err = h.service.ProcessPayment(input)
 	if err != nil {
-		response := helper.APIResponse("Process Notification Failed", http.StatusBadRequest, "error", nil)
+		log.Println("reject payment notification:", err.Error())
+
+		errorMessage := gin.H{"errors": err.Error()}
+		response := helper.APIResponse("Process Notification Failed", http.StatusBadRequest, "error", errorMessage)
 		c.JSON(http.StatusBadRequest, response)
 		return
 	}
 
-	c.JSON(http.StatusOK, input)
+	response := helper.APIResponse("Notification Processed", http.StatusOK, "success", nil)
+	c.JSON(http.StatusOK, response)
 }
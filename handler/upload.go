@@ -0,0 +1,67 @@
+package handler
+
+import (
+	"bwastartup/helper"
+	"bwastartup/imaging"
+	"bwastartup/storage"
+	"bytes"
+	"context"
+	"log"
+	"mime/multipart"
+)
+
+// uploadImage runs an uploaded file through the image pipeline and stores
+// every variant under keyPrefix. The client's file name is never used in
+// the key. It returns the URL of each variant by variant name.
+func uploadImage(ctx context.Context, pipeline *imaging.Pipeline, store storage.Storage, file *multipart.FileHeader, keyPrefix string) (map[string]string, error) {
+	src, err := file.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer src.Close()
+
+	variants, err := pipeline.Process(src)
+	if err != nil {
+		return nil, err
+	}
+
+	name, err := helper.RandomToken(12)
+	if err != nil {
+		return nil, err
+	}
+
+	urls := map[string]string{}
+	for _, variant := range variants {
+		key := keyPrefix + "/" + name + "-" + variant.Name + variant.Extension
+
+		url, err := store.Put(ctx, key, bytes.NewReader(variant.Data), variant.ContentType)
+		if err != nil {
+			deleteImages(ctx, store, urls)
+			return nil, err
+		}
+
+		urls[variant.Name] = url
+	}
+
+	return urls, nil
+}
+
+// deleteImages removes stored variants that ended up unused. Failures are
+// only logged, the request has already failed for another reason.
+func deleteImages(ctx context.Context, store storage.Storage, urls map[string]string) {
+	for _, url := range urls {
+		key, ok := storage.KeyFromURL(store, url)
+		if !ok {
+			continue
+		}
+
+		err := store.Delete(ctx, key)
+		if err != nil {
+			log.Println("delete unused image file:", err.Error())
+		}
+	}
+}
+
+func isImageError(err error) bool {
+	return err == imaging.ErrUnsupportedType || err == imaging.ErrFileTooLarge || err == imaging.ErrTooManyPixels
+}
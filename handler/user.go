@@ -3,23 +3,27 @@ package handler
 import (
 	"bwastartup/auth"
 	"bwastartup/helper"
+	"bwastartup/imaging"
+	"bwastartup/storage"
 	"bwastartup/user"
-	"cloud.google.com/go/storage"
-	"context"
 	"fmt"
-	"io"
 	"net/http"
+	"strconv"
+	"time"
 
+	"github.com/dgrijalva/jwt-go"
 	"github.com/gin-gonic/gin"
 )
 
 type userHandler struct {
-	userService user.Service
-	authService auth.Service
+	userService   user.Service
+	authService   auth.Service
+	storage       storage.Storage
+	imagePipeline *imaging.Pipeline
 }
 
-func NewUserHandler(userService user.Service, authService auth.Service) *userHandler {
-	return &userHandler{userService, authService}
+func NewUserHandler(userService user.Service, authService auth.Service, storage storage.Storage, imagePipeline *imaging.Pipeline) *userHandler {
+	return &userHandler{userService, authService, storage, imagePipeline}
 }
 
 func (h *userHandler) RegisterUser(c *gin.Context) {
@@ -42,14 +46,15 @@ func (h *userHandler) RegisterUser(c *gin.Context) {
 		return
 	}
 
-	token, err := h.authService.GenerateToken(newUser.ID)
+	tokens, err := h.authService.GenerateTokenPair(newUser.ID)
 	if err != nil {
 		response := helper.APIResponse("Register account failed", http.StatusBadRequest, "Error", nil)
 		c.JSON(http.StatusBadRequest, response)
 		return
 	}
 
-	formatter := user.FormatUser(newUser, token)
+	formatter := user.FormatUser(newUser, tokens.AccessToken)
+	formatter.RefreshToken = tokens.RefreshToken
 	response := helper.APIResponse("Account has been Registed", http.StatusOK, "Success", formatter)
 
 	c.JSON(http.StatusOK, response)
@@ -68,27 +73,279 @@ func (h *userHandler) Login(c *gin.Context) {
 		return
 	}
 
+	input.IPAddress = c.ClientIP()
+
 	loggedinUser, err := h.userService.Login(input)
-	if err != nil {
+	if lockoutError, ok := err.(*user.LockoutError); ok {
+		retryAfter := int(time.Until(lockoutError.Until).Seconds()) + 1
+		c.Header("Retry-After", strconv.Itoa(retryAfter))
+
+		errorMessage := gin.H{"errors": lockoutError.Error()}
+		response := helper.APIResponse("Login Failed", http.StatusTooManyRequests, "Error", errorMessage)
+		c.JSON(http.StatusTooManyRequests, response)
+		return
+	}
+
+	if err == user.ErrUserBanned {
 		errorMessage := gin.H{"errors": err.Error()}
+		response := helper.APIResponse("Login Failed", http.StatusForbidden, "Error", errorMessage)
+		c.JSON(http.StatusForbidden, response)
+		return
+	}
+
+	if err != nil {
+		errorMessage := gin.H{"errors": user.ErrInvalidCredentials.Error()}
 		response := helper.APIResponse("Login Failed", http.StatusBadRequest, "Error", errorMessage)
 		c.JSON(http.StatusBadRequest, response)
 		return
 	}
 
-	token, err := h.authService.GenerateToken(loggedinUser.ID)
+	tokens, err := h.authService.GenerateTokenPair(loggedinUser.ID)
 	if err != nil {
 		response := helper.APIResponse("Login failed", http.StatusBadRequest, "Error", nil)
 		c.JSON(http.StatusBadRequest, response)
 		return
 	}
 
-	formatter := user.FormatUser(loggedinUser, token)
+	formatter := user.FormatUser(loggedinUser, tokens.AccessToken)
+	formatter.RefreshToken = tokens.RefreshToken
 	response := helper.APIResponse("Login Success", http.StatusOK, "Success", formatter)
 
 	c.JSON(http.StatusOK, response)
 }
 
+func (h *userHandler) RefreshSession(c *gin.Context) {
+	var input auth.RefreshTokenInput
+
+	err := c.ShouldBindJSON(&input)
+	if err != nil {
+		errors := helper.FormatValidationError(err)
+		errorMessage := gin.H{"errors": errors}
+
+		response := helper.APIResponse("Refresh Session Failed", http.StatusUnprocessableEntity, "Error", errorMessage)
+		c.JSON(http.StatusUnprocessableEntity, response)
+		return
+	}
+
+	tokens, err := h.authService.RefreshToken(input.RefreshToken)
+	if err != nil {
+		errorMessage := gin.H{"errors": err.Error()}
+		response := helper.APIResponse("Refresh Session Failed", http.StatusUnauthorized, "Error", errorMessage)
+		c.JSON(http.StatusUnauthorized, response)
+		return
+	}
+
+	refreshedUser, err := h.userService.GetUserByID(tokens.UserID)
+	if err != nil {
+		response := helper.APIResponse("Refresh Session Failed", http.StatusUnauthorized, "Error", nil)
+		c.JSON(http.StatusUnauthorized, response)
+		return
+	}
+
+	formatter := user.FormatUser(refreshedUser, tokens.AccessToken)
+	formatter.RefreshToken = tokens.RefreshToken
+	response := helper.APIResponse("Session Refreshed", http.StatusOK, "Success", formatter)
+
+	c.JSON(http.StatusOK, response)
+}
+
+func (h *userHandler) Logout(c *gin.Context) {
+	currentToken := c.MustGet("currentToken").(*jwt.Token)
+
+	err := h.authService.RevokeToken(currentToken)
+	if err != nil {
+		response := helper.APIResponse("Logout Failed", http.StatusBadRequest, "Error", nil)
+		c.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	response := helper.APIResponse("Logout Success", http.StatusOK, "Success", nil)
+	c.JSON(http.StatusOK, response)
+}
+
+func (h *userHandler) LogoutEverywhere(c *gin.Context) {
+	currentUser := c.MustGet("currentUser").(user.User)
+
+	err := h.authService.RevokeAllTokens(currentUser.ID)
+	if err != nil {
+		response := helper.APIResponse("Logout Failed", http.StatusBadRequest, "Error", nil)
+		c.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	response := helper.APIResponse("Logged Out From All Sessions", http.StatusOK, "Success", nil)
+	c.JSON(http.StatusOK, response)
+}
+
+func (h *userHandler) RequestPasswordReset(c *gin.Context) {
+	var input user.ForgotPasswordInput
+
+	err := c.ShouldBindJSON(&input)
+	if err != nil {
+		errors := helper.FormatValidationError(err)
+		errorMessage := gin.H{"errors": errors}
+
+		response := helper.APIResponse("Password Reset Request Failed", http.StatusUnprocessableEntity, "Error", errorMessage)
+		c.JSON(http.StatusUnprocessableEntity, response)
+		return
+	}
+
+	err = h.userService.RequestPasswordReset(input)
+	if err != nil {
+		response := helper.APIResponse("Password Reset Request Failed", http.StatusBadRequest, "Error", nil)
+		c.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	response := helper.APIResponse("If the email is registered, a password reset link has been sent", http.StatusOK, "Success", nil)
+	c.JSON(http.StatusOK, response)
+}
+
+func (h *userHandler) ResetPassword(c *gin.Context) {
+	var inputToken user.ResetPasswordTokenInput
+
+	err := c.ShouldBindUri(&inputToken)
+	if err != nil {
+		response := helper.APIResponse("Password Reset Failed", http.StatusBadRequest, "Error", nil)
+		c.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	var inputData user.ResetPasswordInput
+
+	err = c.ShouldBindJSON(&inputData)
+	if err != nil {
+		errors := helper.FormatValidationError(err)
+		errorMessage := gin.H{"errors": errors}
+
+		response := helper.APIResponse("Password Reset Failed", http.StatusUnprocessableEntity, "Error", errorMessage)
+		c.JSON(http.StatusUnprocessableEntity, response)
+		return
+	}
+
+	updatedUser, err := h.userService.ResetPassword(inputToken, inputData)
+	if err != nil {
+		errorMessage := gin.H{"errors": err.Error()}
+		response := helper.APIResponse("Password Reset Failed", http.StatusBadRequest, "Error", errorMessage)
+		c.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	err = h.authService.RevokeAllTokens(updatedUser.ID)
+	if err != nil {
+		response := helper.APIResponse("Password Reset Failed", http.StatusBadRequest, "Error", nil)
+		c.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	formatter := user.FormatUser(updatedUser, "")
+	response := helper.APIResponse("Password has been Reset", http.StatusOK, "Success", formatter)
+
+	c.JSON(http.StatusOK, response)
+}
+
+func (h *userHandler) SendEmailVerification(c *gin.Context) {
+	currentUser := c.MustGet("currentUser").(user.User)
+
+	err := h.userService.SendEmailVerification(currentUser)
+	if err != nil {
+		errorMessage := gin.H{"errors": err.Error()}
+		response := helper.APIResponse("Send Email Verification Failed", http.StatusBadRequest, "Error", errorMessage)
+		c.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	response := helper.APIResponse("Verification email has been sent", http.StatusOK, "Success", nil)
+	c.JSON(http.StatusOK, response)
+}
+
+func (h *userHandler) VerifyEmail(c *gin.Context) {
+	var input user.VerifyEmailInput
+
+	err := c.ShouldBindUri(&input)
+	if err != nil {
+		response := helper.APIResponse("Email Verification Failed", http.StatusBadRequest, "Error", nil)
+		c.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	verifiedUser, err := h.userService.VerifyEmail(input)
+	if err != nil {
+		errorMessage := gin.H{"errors": err.Error()}
+		response := helper.APIResponse("Email Verification Failed", http.StatusBadRequest, "Error", errorMessage)
+		c.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	formatter := user.FormatUser(verifiedUser, "")
+	response := helper.APIResponse("Email has been Verified", http.StatusOK, "Success", formatter)
+
+	c.JSON(http.StatusOK, response)
+}
+
+func (h *userHandler) GetUsers(c *gin.Context) {
+	users, err := h.userService.GetUsers()
+	if err != nil {
+		response := helper.APIResponse("Failed to Get Users", http.StatusBadRequest, "Error", nil)
+		c.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	response := helper.APIResponse("List of Users", http.StatusOK, "Success", user.FormatAdminUsers(users))
+	c.JSON(http.StatusOK, response)
+}
+
+func (h *userHandler) BanUser(c *gin.Context) {
+	var input user.GetUserDetailInput
+
+	err := c.ShouldBindUri(&input)
+	if err != nil {
+		response := helper.APIResponse("Failed to Ban User", http.StatusBadRequest, "Error", nil)
+		c.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	bannedUser, err := h.userService.BanUser(input)
+	if err != nil {
+		errorMessage := gin.H{"errors": err.Error()}
+		response := helper.APIResponse("Failed to Ban User", http.StatusBadRequest, "Error", errorMessage)
+		c.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	err = h.authService.RevokeAllTokens(bannedUser.ID)
+	if err != nil {
+		response := helper.APIResponse("Failed to Ban User", http.StatusBadRequest, "Error", nil)
+		c.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	response := helper.APIResponse("User has been Banned", http.StatusOK, "Success", user.FormatAdminUser(bannedUser))
+	c.JSON(http.StatusOK, response)
+}
+
+func (h *userHandler) UnbanUser(c *gin.Context) {
+	var input user.GetUserDetailInput
+
+	err := c.ShouldBindUri(&input)
+	if err != nil {
+		response := helper.APIResponse("Failed to Unban User", http.StatusBadRequest, "Error", nil)
+		c.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	unbannedUser, err := h.userService.UnbanUser(input)
+	if err != nil {
+		errorMessage := gin.H{"errors": err.Error()}
+		response := helper.APIResponse("Failed to Unban User", http.StatusBadRequest, "Error", errorMessage)
+		c.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	response := helper.APIResponse("User has been Unbanned", http.StatusOK, "Success", user.FormatAdminUser(unbannedUser))
+	c.JSON(http.StatusOK, response)
+}
+
 func (h *userHandler) CheckEmailAvailability(c *gin.Context) {
 	var input user.CheckEmailInput
 
@@ -127,7 +384,7 @@ func (h *userHandler) CheckEmailAvailability(c *gin.Context) {
 }
 
 func (h *userHandler) UploadAvatar(c *gin.Context) {
-	file, err := c.Request.MultipartReader()
+	file, err := c.FormFile("avatar")
 	if err != nil {
 		data := gin.H{"is_uploaded": false}
 		response := helper.APIResponse("Upload avatar image failed", http.StatusBadRequest, "error", data)
@@ -136,93 +393,122 @@ func (h *userHandler) UploadAvatar(c *gin.Context) {
 		return
 	}
 
-	var foundImage bool
-	var fileName string
 	currentUser := c.MustGet("currentUser").(user.User)
 	userID := currentUser.ID
-	for {
-		next, err := file.NextPart()
-		if err == io.EOF {
-			break
-		}
-		if err != nil {
-			data := gin.H{"is_uploaded": false}
-			response := helper.APIResponse("Upload avatar image failed", http.StatusBadRequest, "error", data)
 
-			c.JSON(http.StatusBadRequest, response)
-			return
-		}
-		if next.FormName() == "avatar" {
-			foundImage = true
-			ctx := context.Background()
-			client, err := storage.NewClient(ctx)
-			if err != nil {
-				data := gin.H{"is_uploaded": false}
-				response := helper.APIResponse("Upload avatar image failed", http.StatusBadRequest, "error", data)
-
-				c.JSON(http.StatusBadRequest, response)
-				return
-			}
-
-			bucket := client.Bucket("donation_alert")
-			w := bucket.Object(next.FileName()).NewWriter(ctx)
-			if _, err := io.Copy(w, next); err != nil {
-				data := gin.H{"is_uploaded": false}
-				response := helper.APIResponse("Upload avatar image failed", http.StatusBadRequest, "error", data)
-
-				c.JSON(http.StatusBadRequest, response)
-				return
-			}
-			if err := w.Close(); err != nil {
-				data := gin.H{"is_uploaded": false}
-				response := helper.APIResponse("Upload avatar image failed", http.StatusBadRequest, "error", data)
-
-				c.JSON(http.StatusBadRequest, response)
-				return
-			}
-
-			acl := bucket.Object(next.FileName()).ACL()
-			if err := acl.Set(c, storage.AllUsers, storage.RoleReader); err != nil {
-				data := gin.H{"is_uploaded": false}
-				response := helper.APIResponse("Upload avatar image failed", http.StatusBadRequest, "error", data)
-
-				c.JSON(http.StatusBadRequest, response)
-				return
-			}
-			fileName = next.FileName()
-		}
-	}
-	if !foundImage {
+	keyPrefix := fmt.Sprintf("avatars/%d", userID)
+
+	imageURLs, err := uploadImage(c.Request.Context(), h.imagePipeline, h.storage, file, keyPrefix)
+	if err != nil {
 		data := gin.H{"is_uploaded": false}
+		if isImageError(err) {
+			data["errors"] = err.Error()
+		}
+
 		response := helper.APIResponse("Upload avatar image failed", http.StatusBadRequest, "error", data)
 
 		c.JSON(http.StatusBadRequest, response)
 		return
 	}
 
-	//path := fmt.Sprintf("/home/muhammadfahrezam/bwastartup/images/%d-%s", userID, file.Filename)
-	//pathName := fmt.Sprintf("%d-%s", userID, fileName)
-	//err = h.userService.UploadToCloud(file, userID)
+	_, err = h.userService.SaveAvatar(userID, imageURLs["full"])
 	if err != nil {
+		deleteImages(c.Request.Context(), h.storage, imageURLs)
+
 		data := gin.H{"is_uploaded": false}
-		response := helper.APIResponse("Upload avatar image failed", http.StatusBadRequest, "error", data)
+		response := helper.APIResponse("Ups Upload avatar image failed", http.StatusBadRequest, "error", data)
 
 		c.JSON(http.StatusBadRequest, response)
 		return
 	}
 
-	imageUrl := fmt.Sprintf("https://storage.googleapis.com/donation_alert/%s", fileName)
-	_, err = h.userService.SaveAvatar(userID, imageUrl)
+	data := gin.H{"is_uploaded": true}
+	response := helper.APIResponse("Upload avatar image success", http.StatusOK, "success", data)
+
+	c.JSON(http.StatusOK, response)
+}
+
+func (h *userHandler) UpdateUser(c *gin.Context) {
+	var input user.UpdateUserInput
+
+	err := c.ShouldBindJSON(&input)
 	if err != nil {
-		data := gin.H{"is_uploaded": false}
-		response := helper.APIResponse("Ups Upload avatar image failed", http.StatusBadRequest, "error", data)
+		errors := helper.FormatValidationError(err)
+		errorMessage := gin.H{"errors": errors}
+
+		response := helper.APIResponse("Update Profile Failed", http.StatusUnprocessableEntity, "Error", errorMessage)
+		c.JSON(http.StatusUnprocessableEntity, response)
+		return
+	}
+
+	currentUser := c.MustGet("currentUser").(user.User)
 
+	updatedUser, err := h.userService.UpdateUser(currentUser.ID, input)
+	if err != nil {
+		errorMessage := gin.H{"errors": err.Error()}
+		response := helper.APIResponse("Update Profile Failed", http.StatusBadRequest, "Error", errorMessage)
 		c.JSON(http.StatusBadRequest, response)
 		return
 	}
 
-	data := gin.H{"is_uploaded": true}
-	response := helper.APIResponse("Upload avatar image success", http.StatusOK, "success", data)
+	formatter := user.FormatUser(updatedUser, "")
+	response := helper.APIResponse("Profile has been Updated", http.StatusOK, "Success", formatter)
+
+	c.JSON(http.StatusOK, response)
+}
+
+// ChangePassword ends every existing session and hands back a fresh token
+// pair, so only the device that changed the password stays logged in.
+func (h *userHandler) ChangePassword(c *gin.Context) {
+	var input user.ChangePasswordInput
+
+	err := c.ShouldBindJSON(&input)
+	if err != nil {
+		errors := helper.FormatValidationError(err)
+		errorMessage := gin.H{"errors": errors}
+
+		response := helper.APIResponse("Change Password Failed", http.StatusUnprocessableEntity, "Error", errorMessage)
+		c.JSON(http.StatusUnprocessableEntity, response)
+		return
+	}
+
+	currentUser := c.MustGet("currentUser").(user.User)
+
+	updatedUser, err := h.userService.ChangePassword(currentUser.ID, input)
+	if lockoutError, ok := err.(*user.LockoutError); ok {
+		retryAfter := int(time.Until(lockoutError.Until).Seconds()) + 1
+		c.Header("Retry-After", strconv.Itoa(retryAfter))
+
+		errorMessage := gin.H{"errors": lockoutError.Error()}
+		response := helper.APIResponse("Change Password Failed", http.StatusTooManyRequests, "Error", errorMessage)
+		c.JSON(http.StatusTooManyRequests, response)
+		return
+	}
+
+	if err != nil {
+		errorMessage := gin.H{"errors": err.Error()}
+		response := helper.APIResponse("Change Password Failed", http.StatusBadRequest, "Error", errorMessage)
+		c.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	err = h.authService.RevokeAllTokens(updatedUser.ID)
+	if err != nil {
+		response := helper.APIResponse("Change Password Failed", http.StatusBadRequest, "Error", nil)
+		c.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	tokens, err := h.authService.GenerateTokenPair(updatedUser.ID)
+	if err != nil {
+		response := helper.APIResponse("Change Password Failed", http.StatusBadRequest, "Error", nil)
+		c.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	formatter := user.FormatUser(updatedUser, tokens.AccessToken)
+	formatter.RefreshToken = tokens.RefreshToken
+	response := helper.APIResponse("Password has been Changed", http.StatusOK, "Success", formatter)
 
 	c.JSON(http.StatusOK, response)
 }
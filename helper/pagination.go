@@ -0,0 +1,31 @@
+package helper
+
+type Pagination struct {
+	Page       int    `json:"page,omitempty"`
+	Limit      int    `json:"limit"`
+	Total      int64  `json:"total"`
+	TotalPages int    `json:"total_pages"`
+	HasMore    bool   `json:"has_more"`
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+func NewPagination(page int, limit int, total int64) Pagination {
+	pagination := Pagination{
+		Page:  page,
+		Limit: limit,
+		Total: total,
+	}
+
+	if limit > 0 {
+		pagination.TotalPages = int((total + int64(limit) - 1) / int64(limit))
+	}
+
+	return pagination
+}
+
+func PaginatedAPIResponse(message string, code int, status string, data interface{}, pagination Pagination) Response {
+	jsonResponse := APIResponse(message, code, status, data)
+	jsonResponse.Pagination = &pagination
+
+	return jsonResponse
+}
@@ -0,0 +1,186 @@
+package imaging
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	_ "image/gif"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"net/http"
+
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp"
+)
+
+var (
+	ErrUnsupportedType = errors.New("file is not a supported image type")
+	ErrFileTooLarge    = errors.New("image file is too large")
+	ErrTooManyPixels   = errors.New("image dimensions are too large")
+)
+
+var allowedTypes = map[string]bool{
+	"image/jpeg": true,
+	"image/png":  true,
+	"image/gif":  true,
+	"image/webp": true,
+}
+
+// VariantSpec describes one generated size. Crop fills Width x Height
+// exactly, otherwise the image is scaled down to fit inside it. Images are
+// never scaled up.
+type VariantSpec struct {
+	Name   string
+	Width  int
+	Height int
+	Crop   bool
+}
+
+type Variant struct {
+	Name        string
+	ContentType string
+	Extension   string
+	Width       int
+	Height      int
+	Data        []byte
+}
+
+type Config struct {
+	MaxBytes  int64
+	MaxPixels int
+	Variants  []VariantSpec
+}
+
+func DefaultVariants() []VariantSpec {
+	return []VariantSpec{
+		{Name: "thumbnail", Width: 200, Height: 200, Crop: true},
+		{Name: "card", Width: 600, Height: 400, Crop: true},
+		{Name: "full", Width: 1600, Height: 1600},
+	}
+}
+
+type Pipeline struct {
+	config Config
+}
+
+func NewPipeline(config Config) *Pipeline {
+	return &Pipeline{config}
+}
+
+// Process checks that r really holds an image within the configured limits
+// and re-encodes it into every variant. Re-encoding also drops EXIF and any
+// other metadata the client sent along.
+func (p *Pipeline) Process(r io.Reader) ([]Variant, error) {
+	data, err := io.ReadAll(io.LimitReader(r, p.config.MaxBytes+1))
+	if err != nil {
+		return nil, err
+	}
+
+	if int64(len(data)) > p.config.MaxBytes {
+		return nil, ErrFileTooLarge
+	}
+
+	contentType := http.DetectContentType(data)
+	if !allowedTypes[contentType] {
+		return nil, ErrUnsupportedType
+	}
+
+	// Check the dimensions from the header before decoding, so a small file
+	// that expands into a huge bitmap is rejected without allocating it.
+	imageConfig, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, ErrUnsupportedType
+	}
+
+	if imageConfig.Width <= 0 || imageConfig.Height <= 0 || imageConfig.Width*imageConfig.Height > p.config.MaxPixels {
+		return nil, ErrTooManyPixels
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, ErrUnsupportedType
+	}
+
+	if contentType == "image/jpeg" {
+		img = orient(img, jpegOrientation(data))
+	}
+
+	// Formats that can carry transparency stay lossless.
+	keepPNG := contentType == "image/png" || contentType == "image/gif"
+
+	var variants []Variant
+	for _, spec := range p.config.Variants {
+		resized := resize(img, spec)
+
+		variant := Variant{
+			Name:   spec.Name,
+			Width:  resized.Bounds().Dx(),
+			Height: resized.Bounds().Dy(),
+		}
+
+		var buf bytes.Buffer
+		if keepPNG {
+			variant.ContentType = "image/png"
+			variant.Extension = ".png"
+			err = png.Encode(&buf, resized)
+		} else {
+			variant.ContentType = "image/jpeg"
+			variant.Extension = ".jpg"
+			err = jpeg.Encode(&buf, resized, &jpeg.Options{Quality: 85})
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		variant.Data = buf.Bytes()
+		variants = append(variants, variant)
+	}
+
+	return variants, nil
+}
+
+func resize(img image.Image, spec VariantSpec) image.Image {
+	bounds := img.Bounds()
+	srcW, srcH := bounds.Dx(), bounds.Dy()
+	srcRect := bounds
+
+	var dstW, dstH int
+	if spec.Crop {
+		// Cut the largest centered area with the target aspect ratio.
+		cropW, cropH := srcW, srcW*spec.Height/spec.Width
+		if cropH > srcH {
+			cropW, cropH = srcH*spec.Width/spec.Height, srcH
+		}
+
+		x := bounds.Min.X + (srcW-cropW)/2
+		y := bounds.Min.Y + (srcH-cropH)/2
+		srcRect = image.Rect(x, y, x+cropW, y+cropH)
+
+		dstW, dstH = spec.Width, spec.Height
+		if cropW < dstW {
+			dstW, dstH = cropW, cropH
+		}
+	} else {
+		dstW, dstH = srcW, srcH
+		if dstW > spec.Width {
+			dstW, dstH = spec.Width, srcH*spec.Width/srcW
+		}
+		if dstH > spec.Height {
+			dstW, dstH = dstW*spec.Height/dstH, spec.Height
+		}
+	}
+
+	if dstW < 1 {
+		dstW = 1
+	}
+	if dstH < 1 {
+		dstH = 1
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, dstW, dstH))
+	draw.CatmullRom.Scale(dst, dst.Bounds(), img, srcRect, draw.Src, nil)
+
+	return dst
+}
@@ -0,0 +1,40 @@
+package mailer
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestFormatKeepsHeadersOnOneLine(t *testing.T) {
+	tests := []struct {
+		name    string
+		message Message
+	}{
+		{"crlf in subject", Message{To: "backer@example.com", Subject: "New update on Demo\r\nBcc: victim@example.com", Body: "hi"}},
+		{"lf in subject", Message{To: "backer@example.com", Subject: "New update\nBcc: victim@example.com", Body: "hi"}},
+		{"crlf in recipient", Message{To: "backer@example.com\r\nBcc: victim@example.com", Subject: "Hello", Body: "hi"}},
+	}
+
+	for _, test := range tests {
+		formatted := string(format("no-reply@example.com", test.message))
+		headers := formatted[:strings.Index(formatted, "\r\n\r\n")]
+
+		for _, line := range strings.Split(headers, "\r\n") {
+			if strings.HasPrefix(line, "Bcc:") {
+				t.Errorf("%s: injected header %q", test.name, line)
+			}
+		}
+
+		if strings.Count(headers, "\n") != strings.Count(headers, "\r\n") {
+			t.Errorf("%s: bare line feed in headers %q", test.name, headers)
+		}
+	}
+}
+
+func TestFormatEncodesNonASCIISubject(t *testing.T) {
+	formatted := string(format("no-reply@example.com", Message{To: "backer@example.com", Subject: "Kabar terbaru: Sepeda listrik ⚡", Body: "hi"}))
+
+	if !strings.Contains(formatted, "Subject: =?utf-8?q?") {
+		t.Errorf("subject is not Q-encoded:\n%s", formatted)
+	}
+}
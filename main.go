@@ -3,11 +3,18 @@ package main
 import (
 	"bwastartup/auth"
 	"bwastartup/campaign"
+	"bwastartup/campaignupdate"
+	"bwastartup/comment"
+	"bwastartup/config"
 	"bwastartup/handler"
 	"bwastartup/helper"
+	"bwastartup/imaging"
+	"bwastartup/mailer"
 	"bwastartup/payment"
+	"bwastartup/storage"
 	"bwastartup/transaction"
 	"bwastartup/user"
+	"context"
 	"fmt"
 	"github.com/joho/godotenv"
 	"gorm.io/driver/postgres"
@@ -15,6 +22,7 @@ import (
 	"net/http"
 	"os"
 	"strings"
+	"time"
 
 	_ "github.com/GoogleCloudPlatform/cloudsql-proxy/proxy/dialers/mysql"
 	"github.com/dgrijalva/jwt-go"
@@ -45,44 +53,272 @@ func main() {
 		log.Fatal(err.Error())
 	}
 
-	db.AutoMigrate(&user.User{}, &transaction.Transaction{}, &campaign.Campaign{}, &campaign.CampaignImage{})
+	err = campaign.DeduplicateSlugs(db)
+	if err != nil {
+		log.Fatal(err.Error())
+	}
+
+	err = transaction.BackfillCodes(db)
+	if err != nil {
+		log.Fatal(err.Error())
+	}
+
+	err = user.VerifyExistingUsers(db)
+	if err != nil {
+		log.Fatal(err.Error())
+	}
+
+	db.AutoMigrate(&user.User{}, &transaction.Transaction{}, &transaction.PaymentNotification{}, &campaign.Campaign{}, &campaign.CampaignImage{}, &campaign.Reward{}, &campaign.CampaignSlug{}, &auth.RefreshToken{}, &auth.RevokedToken{}, &auth.UserRevocation{}, &user.ActionToken{}, &user.LoginAttempt{}, &user.LoginFailure{}, &campaignupdate.CampaignUpdate{}, &comment.Comment{})
+
+	err = campaign.MigratePerksToRewards(db)
+	if err != nil {
+		log.Fatal(err.Error())
+	}
+
+	err = campaign.BackfillDeadlines(db, time.Now(), config.Duration("LEGACY_CAMPAIGN_GRACE", 30*24*time.Hour))
+	if err != nil {
+		log.Fatal(err.Error())
+	}
 
 	userRepository := user.NewRepository(db)
 	campaignRepository := campaign.NewRepository(db)
 	transactionRepository := transaction.NewRepository(db)
+	authRepository := auth.NewRepository(db)
+	campaignUpdateRepository := campaignupdate.NewRepository(db)
+	commentRepository := comment.NewRepository(db)
+	transactionUnitOfWork := transaction.NewUnitOfWork(db)
+
+	var mail mailer.Mailer
+	switch config.Get("MAILER", "file") {
+	case "smtp":
+		mail = mailer.NewSMTPMailer(config.Get("SMTP_HOST", "localhost"), config.Int("SMTP_PORT", 587), config.Get("SMTP_USER", ""), config.Get("SMTP_PASS", ""), config.Get("MAIL_FROM", "no-reply@bwastartup.com"))
+	case "memory":
+		mail = mailer.NewMemoryMailer()
+	default:
+		mail = mailer.NewFileMailer(config.Get("MAIL_DIR", "mails"), config.Get("MAIL_FROM", "no-reply@bwastartup.com"))
+	}
+
+	appURL := config.Get("APP_URL", "http://localhost:3000")
+
+	bcryptHasher := user.NewBcryptHasher(config.Int("BCRYPT_COST", 12))
+	argon2idHasher := user.NewArgon2idHasher(user.Argon2Params{
+		Memory:      uint32(config.Int("ARGON2_MEMORY_KB", 64*1024)),
+		Iterations:  uint32(config.Int("ARGON2_ITERATIONS", 3)),
+		Parallelism: uint8(config.Int("ARGON2_PARALLELISM", 2)),
+		SaltLength:  16,
+		KeyLength:   32,
+	})
 
-	userService := user.NewService(userRepository)
-	campaignService := campaign.NewService(campaignRepository)
-	authService := auth.NewService()
-	paymentService := payment.NewService()
-	transactionService := transaction.NewService(transactionRepository, campaignRepository, paymentService)
+	passwordHasher := user.NewHasherChain(bcryptHasher, argon2idHasher)
+	if config.Get("PASSWORD_HASHER", "bcrypt") == "argon2id" {
+		passwordHasher = user.NewHasherChain(argon2idHasher, bcryptHasher)
+	}
+
+	var attemptStore user.AttemptStore = user.NewPostgresAttemptStore(db)
+	if config.Get("LOGIN_ATTEMPT_STORE", "postgres") == "memory" {
+		attemptStore = user.NewMemoryAttemptStore()
+	}
+
+	userService := user.NewService(userRepository, passwordHasher, attemptStore, mail, user.Config{
+		AppURL:               appURL,
+		PasswordResetTTL:     config.Duration("PASSWORD_RESET_TTL", time.Hour),
+		EmailVerificationTTL: config.Duration("EMAIL_VERIFICATION_TTL", 48*time.Hour),
+		Throttle: user.ThrottleConfig{
+			MaxAttempts:        config.Int("LOGIN_MAX_ATTEMPTS", 5),
+			MaxAttemptsPerIP:   config.Int("LOGIN_MAX_ATTEMPTS_PER_IP", 100),
+			Window:             config.Duration("LOGIN_ATTEMPT_WINDOW", 24*time.Hour),
+			LockoutDuration:    config.Duration("LOGIN_LOCKOUT", time.Minute),
+			MaxLockoutDuration: config.Duration("LOGIN_MAX_LOCKOUT", time.Hour),
+		},
+	})
+
+	var searchIndex campaign.SearchIndex
+	if config.Get("SEARCH_BACKEND", "postgres") == "memory" {
+		memorySearchIndex := campaign.NewMemorySearchIndex()
+		err = campaign.BuildSearchIndex(campaignRepository, memorySearchIndex)
+		searchIndex = memorySearchIndex
+	} else {
+		postgresSearchIndex := campaign.NewPostgresSearchIndex(db)
+		err = postgresSearchIndex.Migrate()
+		searchIndex = postgresSearchIndex
+	}
+
+	if err != nil {
+		log.Fatal(err.Error())
+	}
+
+	campaignService := campaign.NewService(campaignRepository, searchIndex)
+	var revocationStore auth.RevocationStore = auth.NewPostgresRevocationStore(db)
+	if config.Get("REVOCATION_STORE", "postgres") == "memory" {
+		revocationStore = auth.NewMemoryRevocationStore()
+	}
+
+	var keySet *auth.KeySet
+	if keys := config.Get("JWT_KEYS", ""); keys != "" {
+		keySet, err = auth.LoadKeySet(keys, config.Get("JWT_ACTIVE_KEY_ID", ""))
+	} else {
+		log.Println("JWT_KEYS is not set, signing tokens with an ephemeral key")
+		keySet, err = auth.NewEphemeralKeySet()
+	}
+
+	if err != nil {
+		log.Fatal(err.Error())
+	}
+
+	authService := auth.NewService(authRepository, revocationStore, keySet, auth.Config{
+		AccessTokenTTL:  config.Duration("ACCESS_TOKEN_TTL", 15*time.Minute),
+		RefreshTokenTTL: config.Duration("REFRESH_TOKEN_TTL", 30*24*time.Hour),
+	})
+	apiURL := config.Get("API_URL", "http://localhost:8080")
+
+	paymentRegistry := payment.NewRegistry()
+	paymentRegistry.Register(payment.NewMidtransProvider(payment.MidtransConfig{
+		ServerKey:  config.Get("MIDTRANS_SERVER_KEY", ""),
+		ClientKey:  config.Get("MIDTRANS_CLIENT_KEY", ""),
+		Production: config.Bool("MIDTRANS_PRODUCTION", false),
+	}))
+	paymentRegistry.Register(payment.NewXenditProvider(payment.XenditConfig{
+		SecretKey:     config.Get("XENDIT_SECRET_KEY", ""),
+		CallbackToken: config.Get("XENDIT_CALLBACK_TOKEN", ""),
+		SuccessURL:    config.Get("XENDIT_SUCCESS_URL", appURL),
+		FailureURL:    config.Get("XENDIT_FAILURE_URL", appURL),
+	}))
+
+	// Anyone who knows the fake provider's secret can sign a paid
+	// notification, so without a configured one it is random per process.
+	fakePaymentSecret := config.Get("FAKE_PAYMENT_SECRET", "")
+	if fakePaymentSecret == "" {
+		fakePaymentSecret, err = helper.RandomToken(32)
+		if err != nil {
+			log.Fatal(err.Error())
+		}
+	}
 
-	userHandler := handler.NewUserHandler(userService, authService)
-	campaignHandler := handler.NewCampaignHandler(campaignService)
+	fakePaymentProvider := payment.NewFakeProvider(payment.FakeConfig{
+		CheckoutURL:     apiURL + "/payments/fake",
+		NotificationURL: apiURL + "/api/v1/transactions/notification",
+		Secret:          fakePaymentSecret,
+	})
+	paymentRegistry.Register(fakePaymentProvider)
+
+	paymentProvider := config.Get("PAYMENT_PROVIDER", "midtrans")
+
+	paymentService, err := paymentRegistry.Provider(paymentProvider)
+	if err != nil {
+		log.Fatal(err.Error() + " " + paymentProvider + ", expected one of " + strings.Join(paymentRegistry.Names(), ", "))
+	}
+	transactionService := transaction.NewService(transactionRepository, campaignRepository, paymentService, transactionUnitOfWork)
+	campaignUpdateNotifier := campaignupdate.NewMailNotifier(mail, appURL)
+	campaignUpdateService := campaignupdate.NewService(campaignUpdateRepository, campaignRepository, transactionRepository, campaignUpdateNotifier)
+	commentService := comment.NewService(commentRepository, campaignRepository, transactionRepository)
+
+	localStorageDir := config.Get("STORAGE_LOCAL_DIR", "images")
+
+	var fileStorage storage.Storage
+	switch config.Get("STORAGE_DRIVER", "local") {
+	case "gcs":
+		fileStorage, err = storage.NewGCSStorage(context.Background(), config.Get("GCS_BUCKET", "donation_alert"))
+	case "s3":
+		fileStorage = storage.NewS3Storage(storage.S3Config{
+			Endpoint:  config.Get("S3_ENDPOINT", "https://s3.amazonaws.com"),
+			Region:    config.Get("S3_REGION", "us-east-1"),
+			Bucket:    config.Get("S3_BUCKET", ""),
+			AccessKey: config.Get("S3_ACCESS_KEY", ""),
+			SecretKey: config.Get("S3_SECRET_KEY", ""),
+			PublicURL: config.Get("S3_PUBLIC_URL", ""),
+		})
+	default:
+		fileStorage = storage.NewLocalStorage(localStorageDir, config.Get("STORAGE_PUBLIC_URL", "/images"))
+	}
+
+	if err != nil {
+		log.Fatal(err.Error())
+	}
+
+	imagePipeline := imaging.NewPipeline(imaging.Config{
+		MaxBytes:  int64(config.Int("IMAGE_MAX_BYTES", 10<<20)),
+		MaxPixels: config.Int("IMAGE_MAX_PIXELS", 40_000_000),
+		Variants:  imaging.DefaultVariants(),
+	})
+
+	userHandler := handler.NewUserHandler(userService, authService, fileStorage, imagePipeline)
+	campaignHandler := handler.NewCampaignHandler(campaignService, fileStorage, imagePipeline)
 	transactionHandler := handler.NewTransactionHandler(transactionService)
+	authHandler := handler.NewAuthHandler(authService)
+	campaignUpdateHandler := handler.NewCampaignUpdateHandler(campaignUpdateService)
+	commentHandler := handler.NewCommentHandler(commentService)
+	fakePaymentHandler := handler.NewFakePaymentHandler(fakePaymentProvider)
+
+	requireVerifiedEmail := verifiedEmailMiddleware(config.Bool("REQUIRE_EMAIL_VERIFICATION", true))
+
+	go campaign.RunScheduler(campaignService, config.Duration("CAMPAIGN_SCHEDULER_INTERVAL", time.Minute))
 
 	router := gin.Default()
 	router.Use(cors.Default())
-	router.Static("/images", "/images")
+	router.Static("/images", localStorageDir)
+	router.GET("/.well-known/jwks.json", authHandler.JWKS)
+
+	if paymentProvider == fakePaymentProvider.Name() {
+		router.GET("/payments/fake", fakePaymentHandler.Checkout)
+		router.POST("/payments/fake", fakePaymentHandler.Complete)
+	}
+
 	api := router.Group("/api/v1")
 
 	api.POST("/users", userHandler.RegisterUser)
 	api.POST("/sessions", userHandler.Login)
+	api.POST("/sessions/refresh", userHandler.RefreshSession)
+	api.DELETE("/sessions", authMiddleware(authService, userService), userHandler.Logout)
+	api.DELETE("/sessions/all", authMiddleware(authService, userService), userHandler.LogoutEverywhere)
 	api.POST("/email_checkers", userHandler.CheckEmailAvailability)
+	api.POST("/password_resets", userHandler.RequestPasswordReset)
+	api.PUT("/password_resets/:token", userHandler.ResetPassword)
+	api.POST("/email_verifications", authMiddleware(authService, userService), userHandler.SendEmailVerification)
+	api.POST("/email_verifications/:token", userHandler.VerifyEmail)
 	api.POST("/avatars", authMiddleware(authService, userService), userHandler.UploadAvatar)
 	api.GET("/users/fetch", authMiddleware(authService, userService), userHandler.FetchUser)
+	api.PUT("/users/me", authMiddleware(authService, userService), userHandler.UpdateUser)
+	api.PUT("/users/me/password", authMiddleware(authService, userService), userHandler.ChangePassword)
 
 	api.GET("/campaigns", campaignHandler.GetCampaigns)
-	api.GET("/campaigns/:id", campaignHandler.GetCampaign)
-	api.POST("/campaigns", authMiddleware(authService, userService), campaignHandler.CreateCampaign)
+	api.GET("/campaigns/search", campaignHandler.SearchCampaigns)
+	api.GET("/campaigns/slug/:slug", optionalAuthMiddleware(authService, userService), campaignHandler.GetCampaignBySlug)
+	api.GET("/campaigns/:id", optionalAuthMiddleware(authService, userService), campaignHandler.GetCampaign)
+	api.POST("/campaigns", authMiddleware(authService, userService), requireVerifiedEmail, campaignHandler.CreateCampaign)
 	api.PUT("/campaigns/:id", authMiddleware(authService, userService), campaignHandler.UpdateCampaign)
+	api.POST("/campaigns/:id/publish", authMiddleware(authService, userService), campaignHandler.PublishCampaign)
+	api.POST("/campaigns/:id/close", authMiddleware(authService, userService), campaignHandler.CloseCampaign)
+	api.GET("/campaigns/:id/rewards", optionalAuthMiddleware(authService, userService), campaignHandler.GetRewards)
+	api.POST("/campaigns/:id/rewards", authMiddleware(authService, userService), campaignHandler.CreateReward)
+	api.PUT("/campaigns/:id/rewards/:reward_id", authMiddleware(authService, userService), campaignHandler.UpdateReward)
+	api.DELETE("/campaigns/:id/rewards/:reward_id", authMiddleware(authService, userService), campaignHandler.DeleteReward)
+	api.GET("/campaigns/:id/updates", optionalAuthMiddleware(authService, userService), campaignUpdateHandler.GetCampaignUpdates)
+	api.POST("/campaigns/:id/updates", authMiddleware(authService, userService), campaignUpdateHandler.CreateCampaignUpdate)
+	api.GET("/campaigns/:id/comments", optionalAuthMiddleware(authService, userService), commentHandler.GetComments)
+	api.POST("/campaigns/:id/comments", authMiddleware(authService, userService), requireVerifiedEmail, commentHandler.CreateComment)
+	api.PUT("/comments/:id", authMiddleware(authService, userService), commentHandler.UpdateComment)
+	api.DELETE("/comments/:id", authMiddleware(authService, userService), commentHandler.DeleteComment)
+	api.POST("/comments/:id/hide", authMiddleware(authService, userService), commentHandler.HideComment)
+	api.DELETE("/comments/:id/hide", authMiddleware(authService, userService), commentHandler.UnhideComment)
 	api.POST("/campaign-images", authMiddleware(authService, userService), campaignHandler.UploadImage)
+	api.DELETE("/campaigns/:id/images/:image_id", authMiddleware(authService, userService), campaignHandler.DeleteImage)
+	api.PUT("/campaigns/:id/images/:image_id/primary", authMiddleware(authService, userService), campaignHandler.SetPrimaryImage)
+	api.PUT("/campaigns/:id/images/order", authMiddleware(authService, userService), campaignHandler.ReorderImages)
 
 	api.GET("/campaigns/:id/transactions", authMiddleware(authService, userService), transactionHandler.GetCampaignTransaction)
 	api.GET("/transactions", authMiddleware(authService, userService), transactionHandler.GetUserTransactions)
-	api.POST("/transactions", authMiddleware(authService, userService), transactionHandler.CreateTransaction)
+	api.GET("/transactions/code/:code", authMiddleware(authService, userService), transactionHandler.GetTransactionByCode)
+	api.POST("/transactions", authMiddleware(authService, userService), requireVerifiedEmail, transactionHandler.CreateTransaction)
 	api.POST("/transactions/notification", transactionHandler.GetNotification)
 
+	admin := api.Group("/admin", authMiddleware(authService, userService), requireRole(user.RoleAdmin))
+	admin.GET("/users", userHandler.GetUsers)
+	admin.POST("/users/:id/ban", userHandler.BanUser)
+	admin.DELETE("/users/:id/ban", userHandler.UnbanUser)
+	admin.PUT("/campaigns/:id", campaignHandler.UpdateCampaign)
+	admin.POST("/campaigns/:id/unpublish", campaignHandler.UnpublishCampaign)
+	admin.GET("/transactions", transactionHandler.GetTransactions)
+
 	router.Run()
 }
 
@@ -103,6 +339,13 @@ func authMiddleware(authService auth.Service, userService user.Service) gin.Hand
 		}
 
 		token, err := authService.ValidateToken(tokenString)
+		if err == auth.ErrTokenExpired {
+			errorMessage := gin.H{"errors": err.Error()}
+			response := helper.APIResponse("Token Expired", http.StatusUnauthorized, "Error", errorMessage)
+			c.AbortWithStatusJSON(http.StatusUnauthorized, response)
+			return
+		}
+
 		if err != nil {
 			response := helper.APIResponse("Unauthorization", http.StatusUnauthorized, "Error", nil)
 			c.AbortWithStatusJSON(http.StatusUnauthorized, response)
@@ -119,13 +362,58 @@ func authMiddleware(authService auth.Service, userService user.Service) gin.Hand
 		userID := int(claim["user_id"].(float64))
 
 		user, err := userService.GetUserByID(userID)
-		if err != nil {
+		if err != nil || user.BannedAt != nil {
 			response := helper.APIResponse("Unauthorization", http.StatusUnauthorized, "Error", nil)
 			c.AbortWithStatusJSON(http.StatusUnauthorized, response)
 			return
 		}
 
 		c.Set("currentUser", user)
+		c.Set("currentToken", token)
 	}
 
 }
+
+// optionalAuthMiddleware lets anonymous requests through without a
+// currentUser, but still rejects a bad token when one is sent.
+func optionalAuthMiddleware(authService auth.Service, userService user.Service) gin.HandlerFunc {
+	requireAuth := authMiddleware(authService, userService)
+
+	return func(c *gin.Context) {
+		if c.GetHeader("Authorization") == "" {
+			return
+		}
+
+		requireAuth(c)
+	}
+}
+
+// requireRole must run after authMiddleware.
+func requireRole(role string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		currentUser := c.MustGet("currentUser").(user.User)
+		if currentUser.Role != role {
+			response := helper.APIResponse("Forbidden", http.StatusForbidden, "Error", nil)
+			c.AbortWithStatusJSON(http.StatusForbidden, response)
+			return
+		}
+	}
+}
+
+// verifiedEmailMiddleware must run after authMiddleware. It is a no-op when
+// verification is not required, so deployments can switch the check off.
+func verifiedEmailMiddleware(required bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !required {
+			return
+		}
+
+		currentUser := c.MustGet("currentUser").(user.User)
+		if currentUser.VerifiedAt == nil {
+			errorMessage := gin.H{"errors": "email address has not been verified"}
+			response := helper.APIResponse("Email Verification Required", http.StatusForbidden, "Error", errorMessage)
+			c.AbortWithStatusJSON(http.StatusForbidden, response)
+			return
+		}
+	}
+}
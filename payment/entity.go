@@ -4,3 +4,20 @@ type Transaction struct {
 	ID     int
 	Amount int
 }
+
+// Outcomes a provider can report in a notification.
+const (
+	StatusPaid     = "paid"
+	StatusFailed   = "failed"
+	StatusRefunded = "refunded"
+)
+
+// Notification is a payment notification after the provider has verified
+// and parsed it. Status is empty for notifications that change nothing,
+// like a payment that is still pending.
+type Notification struct {
+	OrderID        string
+	Status         string
+	ProviderStatus string
+	Amount         int
+}
@@ -0,0 +1,184 @@
+package payment
+
+import (
+	"bwastartup/user"
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+type FakeConfig struct {
+	// CheckoutURL is where the checkout page is served, see Checkout.
+	CheckoutURL string
+	// NotificationURL is where notifications are posted back, normally the
+	// transactions notification endpoint of this API.
+	NotificationURL string
+	Secret          string
+}
+
+// Checkout serves the checkout page of the fake provider and sends the
+// notification once the backer picks an outcome.
+type Checkout interface {
+	CheckoutPage(w io.Writer, input FakeCheckoutInput) error
+	Complete(input FakeCheckoutInput) error
+}
+
+// fakeProvider is a local stand-in for a payment gateway, so the pledge flow
+// runs end to end without the network. Checkout URLs and notifications are
+// signed with the secret and contain nothing random, so the same
+// transaction always gets the same URL and the same notification.
+type fakeProvider struct {
+	config FakeConfig
+	client *http.Client
+}
+
+func NewFakeProvider(config FakeConfig) *fakeProvider {
+	return &fakeProvider{config, &http.Client{Timeout: 30 * time.Second}}
+}
+
+type fakeNotification struct {
+	OrderID   string `json:"order_id"`
+	Status    string `json:"status"`
+	Amount    int    `json:"amount"`
+	Signature string `json:"signature"`
+}
+
+var fakeStatuses = map[string]bool{
+	StatusPaid:     true,
+	StatusFailed:   true,
+	StatusRefunded: true,
+}
+
+func (p *fakeProvider) Name() string {
+	return "fake"
+}
+
+func (p *fakeProvider) GetPaymentURL(transaction Transaction, user user.User) (string, error) {
+	orderID := strconv.Itoa(transaction.ID)
+	amount := strconv.Itoa(transaction.Amount)
+
+	query := url.Values{}
+	query.Set("order_id", orderID)
+	query.Set("amount", amount)
+	query.Set("signature", p.sign(orderID, amount))
+
+	return p.config.CheckoutURL + "?" + query.Encode(), nil
+}
+
+func (p *fakeProvider) ParseNotification(header http.Header, body []byte) (Notification, error) {
+	var input fakeNotification
+
+	err := json.Unmarshal(body, &input)
+	if err != nil {
+		return Notification{}, ErrInvalidPayload
+	}
+
+	if !hmac.Equal([]byte(input.Signature), []byte(p.sign(input.OrderID, input.Status, strconv.Itoa(input.Amount)))) {
+		return Notification{}, ErrInvalidSignature
+	}
+
+	if !fakeStatuses[input.Status] {
+		return Notification{}, ErrInvalidPayload
+	}
+
+	notification := Notification{}
+	notification.OrderID = input.OrderID
+	notification.Status = input.Status
+	notification.ProviderStatus = input.Status
+	notification.Amount = input.Amount
+
+	return notification, nil
+}
+
+func (p *fakeProvider) CheckoutPage(w io.Writer, input FakeCheckoutInput) error {
+	if !p.validCheckout(input) {
+		return ErrInvalidSignature
+	}
+
+	return fakeCheckoutTemplate.Execute(w, input)
+}
+
+// Complete posts the notification for the outcome the backer picked and
+// waits for the API to accept it.
+func (p *fakeProvider) Complete(input FakeCheckoutInput) error {
+	if !p.validCheckout(input) {
+		return ErrInvalidSignature
+	}
+
+	if !fakeStatuses[input.Status] {
+		return ErrInvalidPayload
+	}
+
+	body, err := json.Marshal(fakeNotification{
+		OrderID:   input.OrderID,
+		Status:    input.Status,
+		Amount:    input.Amount,
+		Signature: p.sign(input.OrderID, input.Status, strconv.Itoa(input.Amount)),
+	})
+	if err != nil {
+		return err
+	}
+
+	resp, err := p.client.Post(p.config.NotificationURL, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("fake payment notification: %s: %s", resp.Status, message)
+	}
+
+	return nil
+}
+
+func (p *fakeProvider) validCheckout(input FakeCheckoutInput) bool {
+	expected := p.sign(input.OrderID, strconv.Itoa(input.Amount))
+
+	return hmac.Equal([]byte(input.Signature), []byte(expected))
+}
+
+// sign is the hex HMAC-SHA256 of the parts joined with colons.
+func (p *fakeProvider) sign(parts ...string) string {
+	mac := hmac.New(sha256.New, []byte(p.config.Secret))
+
+	for i, part := range parts {
+		if i > 0 {
+			mac.Write([]byte(":"))
+		}
+		mac.Write([]byte(part))
+	}
+
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+var fakeCheckoutTemplate = template.Must(template.New("checkout").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Fake checkout #{{.OrderID}}</title>
+</head>
+<body>
+<h1>Fake checkout</h1>
+<p>Order {{.OrderID}}, amount {{.Amount}}.</p>
+<form method="post">
+<input type="hidden" name="order_id" value="{{.OrderID}}">
+<input type="hidden" name="amount" value="{{.Amount}}">
+<input type="hidden" name="signature" value="{{.Signature}}">
+<button type="submit" name="status" value="paid">Pay</button>
+<button type="submit" name="status" value="failed">Fail</button>
+<button type="submit" name="status" value="refunded">Refund</button>
+</form>
+</body>
+</html>
+`))
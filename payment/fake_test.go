@@ -0,0 +1,119 @@
+package payment
+
+import (
+	"bwastartup/user"
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// checkoutInput reads the checkout input back out of a payment URL, the way
+// the checkout page handler binds it.
+func checkoutInput(t *testing.T, paymentURL string) FakeCheckoutInput {
+	parsed, err := url.Parse(paymentURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	query := parsed.Query()
+	amount, _ := strconv.Atoi(query.Get("amount"))
+
+	return FakeCheckoutInput{OrderID: query.Get("order_id"), Amount: amount, Signature: query.Get("signature")}
+}
+
+func TestFakeProviderRoundTrip(t *testing.T) {
+	var provider *fakeProvider
+	var notifications []Notification
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+
+		notification, err := provider.ParseNotification(r.Header, body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		notifications = append(notifications, notification)
+	}))
+	defer server.Close()
+
+	provider = NewFakeProvider(FakeConfig{CheckoutURL: "http://api.test/payments/fake", NotificationURL: server.URL, Secret: "secret"})
+
+	paymentURL, err := provider.GetPaymentURL(Transaction{ID: 7, Amount: 5000}, user.User{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	again, _ := provider.GetPaymentURL(Transaction{ID: 7, Amount: 5000}, user.User{})
+	if paymentURL != again {
+		t.Errorf("GetPaymentURL is not deterministic: %q and %q", paymentURL, again)
+	}
+
+	input := checkoutInput(t, paymentURL)
+
+	var page bytes.Buffer
+	err = provider.CheckoutPage(&page, input)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.Contains(page.String(), `value="paid"`) {
+		t.Errorf("checkout page has no pay button:\n%s", page.String())
+	}
+
+	for _, status := range []string{StatusPaid, StatusFailed, StatusRefunded} {
+		input.Status = status
+
+		err = provider.Complete(input)
+		if err != nil {
+			t.Fatalf("Complete(%s): %v", status, err)
+		}
+
+		notification := notifications[len(notifications)-1]
+		if notification.OrderID != "7" || notification.Amount != 5000 || notification.Status != status {
+			t.Errorf("Complete(%s) sent %+v", status, notification)
+		}
+	}
+
+	input.Status = "settled"
+	if err := provider.Complete(input); err != ErrInvalidPayload {
+		t.Errorf("Complete with unknown status = %v, want ErrInvalidPayload", err)
+	}
+
+	input.Status = StatusPaid
+	input.Amount = 1
+	if err := provider.Complete(input); err != ErrInvalidSignature {
+		t.Errorf("Complete with changed amount = %v, want ErrInvalidSignature", err)
+	}
+
+	if err := provider.CheckoutPage(io.Discard, input); err != ErrInvalidSignature {
+		t.Errorf("CheckoutPage with changed amount = %v, want ErrInvalidSignature", err)
+	}
+}
+
+func TestFakeProviderRejectsForgedNotification(t *testing.T) {
+	provider := NewFakeProvider(FakeConfig{Secret: "secret"})
+
+	tests := []struct {
+		name string
+		body string
+		want error
+	}{
+		{"unsigned", `{"order_id":"7","status":"paid","amount":5000}`, ErrInvalidSignature},
+		{"wrong signature", `{"order_id":"7","status":"paid","amount":5000,"signature":"00"}`, ErrInvalidSignature},
+		{"not json", `order_id=7`, ErrInvalidPayload},
+	}
+
+	for _, test := range tests {
+		_, err := provider.ParseNotification(http.Header{}, []byte(test.body))
+		if err != test.want {
+			t.Errorf("%s: err = %v, want %v", test.name, err, test.want)
+		}
+	}
+}
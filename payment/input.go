@@ -0,0 +1,8 @@
+package payment
+
+type FakeCheckoutInput struct {
+	OrderID   string `form:"order_id" binding:"required"`
+	Amount    int    `form:"amount" binding:"required"`
+	Signature string `form:"signature" binding:"required"`
+	Status    string `form:"status"`
+}
@@ -0,0 +1,132 @@
+package payment
+
+import (
+	"bwastartup/user"
+	"crypto/sha512"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/veritrans/go-midtrans"
+)
+
+type MidtransConfig struct {
+	ServerKey  string
+	ClientKey  string
+	Production bool
+}
+
+type midtransProvider struct {
+	config MidtransConfig
+}
+
+func NewMidtransProvider(config MidtransConfig) *midtransProvider {
+	return &midtransProvider{config}
+}
+
+type midtransNotification struct {
+	TransactionStatus string `json:"transaction_status"`
+	OrderID           string `json:"order_id"`
+	PaymentType       string `json:"payment_type"`
+	FraudStatus       string `json:"fraud_status"`
+	StatusCode        string `json:"status_code"`
+	GrossAmount       string `json:"gross_amount"`
+	SignatureKey      string `json:"signature_key"`
+}
+
+func (p *midtransProvider) Name() string {
+	return "midtrans"
+}
+
+func (p *midtransProvider) GetPaymentURL(transaction Transaction, user user.User) (string, error) {
+	midclient := midtrans.NewClient()
+	midclient.ServerKey = p.config.ServerKey
+	midclient.ClientKey = p.config.ClientKey
+	midclient.APIEnvType = midtrans.Sandbox
+	if p.config.Production {
+		midclient.APIEnvType = midtrans.Production
+	}
+
+	snapGateway := midtrans.SnapGateway{
+		Client: midclient,
+	}
+
+	snapReq := &midtrans.SnapReq{
+		CustomerDetail: &midtrans.CustDetail{
+			Email: user.Email,
+			FName: user.Name,
+		},
+		TransactionDetails: midtrans.TransactionDetails{
+			OrderID:  strconv.Itoa(transaction.ID),
+			GrossAmt: int64(transaction.Amount),
+		},
+	}
+
+	snapTokenResp, err := snapGateway.GetToken(snapReq)
+	if err != nil {
+		return "", err
+	}
+
+	return snapTokenResp.RedirectURL, nil
+}
+
+func (p *midtransProvider) ParseNotification(header http.Header, body []byte) (Notification, error) {
+	var input midtransNotification
+
+	err := json.Unmarshal(body, &input)
+	if err != nil {
+		return Notification{}, ErrInvalidPayload
+	}
+
+	if !p.verifySignature(input) {
+		return Notification{}, ErrInvalidSignature
+	}
+
+	// Midtrans sends the amount with decimals, e.g. "150000.00".
+	grossAmount, err := strconv.ParseFloat(input.GrossAmount, 64)
+	if err != nil {
+		return Notification{}, ErrInvalidPayload
+	}
+
+	notification := Notification{}
+	notification.OrderID = input.OrderID
+	notification.ProviderStatus = input.TransactionStatus
+	notification.Amount = int(grossAmount)
+
+	if grossAmount != float64(notification.Amount) {
+		return Notification{}, ErrInvalidPayload
+	}
+
+	switch input.TransactionStatus {
+	case "capture":
+		if input.PaymentType == "credit_card" && input.FraudStatus == "accept" {
+			notification.Status = StatusPaid
+		}
+	case "settlement":
+		notification.Status = StatusPaid
+	case "deny", "expire", "cancel":
+		notification.Status = StatusFailed
+	case "refund":
+		notification.Status = StatusRefunded
+	}
+
+	return notification, nil
+}
+
+// verifySignature checks the signature_key Midtrans sends with every
+// notification, the hex SHA-512 of order_id, status_code, gross_amount and
+// the server key. Without a server key anyone could compute it, so nothing
+// verifies.
+func (p *midtransProvider) verifySignature(input midtransNotification) bool {
+	if p.config.ServerKey == "" {
+		return false
+	}
+
+	sum := sha512.Sum512([]byte(input.OrderID + input.StatusCode + input.GrossAmount + p.config.ServerKey))
+	expected := hex.EncodeToString(sum[:])
+
+	return subtle.ConstantTimeCompare([]byte(expected), []byte(strings.ToLower(input.SignatureKey))) == 1
+}
@@ -0,0 +1,91 @@
+package payment
+
+import (
+	"crypto/sha512"
+	"encoding/hex"
+	"encoding/json"
+	"testing"
+)
+
+// midtransBody signs notification like Midtrans does and encodes it.
+func midtransBody(t *testing.T, serverKey string, notification midtransNotification) []byte {
+	notification.SignatureKey = midtransSignature(serverKey, notification)
+
+	body, err := json.Marshal(notification)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return body
+}
+
+func TestMidtransParseNotification(t *testing.T) {
+	provider := NewMidtransProvider(MidtransConfig{ServerKey: "server-key"})
+
+	tests := []struct {
+		name         string
+		notification midtransNotification
+		want         string
+	}{
+		{"settlement", midtransNotification{TransactionStatus: "settlement"}, StatusPaid},
+		{"accepted card capture", midtransNotification{TransactionStatus: "capture", PaymentType: "credit_card", FraudStatus: "accept"}, StatusPaid},
+		{"challenged card capture", midtransNotification{TransactionStatus: "capture", PaymentType: "credit_card", FraudStatus: "challenge"}, ""},
+		{"pending", midtransNotification{TransactionStatus: "pending"}, ""},
+		{"deny", midtransNotification{TransactionStatus: "deny"}, StatusFailed},
+		{"expire", midtransNotification{TransactionStatus: "expire"}, StatusFailed},
+		{"cancel", midtransNotification{TransactionStatus: "cancel"}, StatusFailed},
+		{"refund", midtransNotification{TransactionStatus: "refund"}, StatusRefunded},
+	}
+
+	for _, test := range tests {
+		test.notification.OrderID = "12"
+		test.notification.StatusCode = "200"
+		test.notification.GrossAmount = "150000.00"
+
+		notification, err := provider.ParseNotification(nil, midtransBody(t, "server-key", test.notification))
+		if err != nil {
+			t.Errorf("%s: %v", test.name, err)
+			continue
+		}
+
+		if notification.Status != test.want || notification.OrderID != "12" || notification.Amount != 150000 {
+			t.Errorf("%s: got %+v, want status %q", test.name, notification, test.want)
+		}
+	}
+}
+
+func TestMidtransRejectsInvalidNotification(t *testing.T) {
+	notification := midtransNotification{OrderID: "12", StatusCode: "200", GrossAmount: "150000.00", TransactionStatus: "settlement"}
+
+	tampered := notification
+	tampered.SignatureKey = midtransSignature("server-key", notification)
+	tampered.GrossAmount = "999999.00"
+	tamperedBody, _ := json.Marshal(tampered)
+
+	tests := []struct {
+		name      string
+		serverKey string
+		body      []byte
+		want      error
+	}{
+		{"signed with another key", "server-key", midtransBody(t, "other-key", notification), ErrInvalidSignature},
+		{"no server key configured", "", midtransBody(t, "", notification), ErrInvalidSignature},
+		{"tampered amount", "server-key", tamperedBody, ErrInvalidSignature},
+		{"fractional amount", "server-key", midtransBody(t, "server-key", midtransNotification{OrderID: "12", StatusCode: "200", GrossAmount: "150000.50", TransactionStatus: "settlement"}), ErrInvalidPayload},
+		{"not json", "server-key", []byte("order_id=12"), ErrInvalidPayload},
+	}
+
+	for _, test := range tests {
+		provider := NewMidtransProvider(MidtransConfig{ServerKey: test.serverKey})
+
+		_, err := provider.ParseNotification(nil, test.body)
+		if err != test.want {
+			t.Errorf("%s: err = %v, want %v", test.name, err, test.want)
+		}
+	}
+}
+
+func midtransSignature(serverKey string, notification midtransNotification) string {
+	sum := sha512.Sum512([]byte(notification.OrderID + notification.StatusCode + notification.GrossAmount + serverKey))
+	return hex.EncodeToString(sum[:])
+}
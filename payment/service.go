@@ -2,47 +2,55 @@ package payment
 
 import (
 	"bwastartup/user"
-	"strconv"
-
-	"github.com/veritrans/go-midtrans"
+	"errors"
+	"net/http"
+	"sort"
 )
 
-type service struct {
-}
+var (
+	ErrUnknownProvider  = errors.New("unknown payment provider")
+	ErrInvalidSignature = errors.New("notification signature is invalid")
+	ErrInvalidPayload   = errors.New("notification payload is invalid")
+)
 
+// Service is one payment gateway. GetPaymentURL starts a payment and returns
+// the page the backer pays on. ParseNotification reads a notification the
+// gateway posted back and rejects it unless it provably came from there.
 type Service interface {
+	Name() string
 	GetPaymentURL(transaction Transaction, user user.User) (string, error)
+	ParseNotification(header http.Header, body []byte) (Notification, error)
 }
 
-func NewService() *service {
-	return &service{}
+// Registry holds the configured providers by name.
+type Registry struct {
+	providers map[string]Service
 }
 
-func (s *service) GetPaymentURL(transaction Transaction, user user.User) (string, error) {
-	midclient := midtrans.NewClient()
-	midclient.ServerKey = "SB-Mid-server-Fg6xbTvgh5i2n7MK_-B8nPhW"
-	midclient.ClientKey = "SB-Mid-client-vx8lA2DVbJjqi35c"
-	midclient.APIEnvType = midtrans.Sandbox
+func NewRegistry() *Registry {
+	return &Registry{map[string]Service{}}
+}
 
-	snapGateway := midtrans.SnapGateway{
-		Client: midclient,
-	}
+func (r *Registry) Register(provider Service) {
+	r.providers[provider.Name()] = provider
+}
 
-	snapReq := &midtrans.SnapReq{
-		CustomerDetail: &midtrans.CustDetail{
-			Email: user.Email,
-			FName: user.Name,
-		},
-		TransactionDetails: midtrans.TransactionDetails{
-			OrderID:  strconv.Itoa(transaction.ID),
-			GrossAmt: int64(transaction.Amount),
-		},
+func (r *Registry) Provider(name string) (Service, error) {
+	provider, ok := r.providers[name]
+	if !ok {
+		return nil, ErrUnknownProvider
 	}
 
-	snapTokenResp, err := snapGateway.GetToken(snapReq)
-	if err != nil {
-		return "", err
+	return provider, nil
+}
+
+func (r *Registry) Names() []string {
+	var names []string
+	for name := range r.providers {
+		names = append(names, name)
 	}
 
-	return snapTokenResp.RedirectURL, nil
+	sort.Strings(names)
+
+	return names
 }
@@ -0,0 +1,132 @@
+package payment
+
+import (
+	"bwastartup/user"
+	"bytes"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type XenditConfig struct {
+	SecretKey string
+	// CallbackToken is the verification token from the Xendit dashboard,
+	// sent back in the x-callback-token header of every callback.
+	CallbackToken string
+	// SuccessURL and FailureURL are where backers land after paying.
+	SuccessURL string
+	FailureURL string
+}
+
+// xenditProvider takes payments through Xendit invoices, see
+// https://developers.xendit.co/api-reference/#create-invoice
+type xenditProvider struct {
+	config   XenditConfig
+	endpoint string
+	client   *http.Client
+}
+
+func NewXenditProvider(config XenditConfig) *xenditProvider {
+	return &xenditProvider{config, "https://api.xendit.co", &http.Client{Timeout: 30 * time.Second}}
+}
+
+type xenditInvoiceRequest struct {
+	ExternalID         string `json:"external_id"`
+	Amount             int    `json:"amount"`
+	PayerEmail         string `json:"payer_email,omitempty"`
+	Description        string `json:"description"`
+	Currency           string `json:"currency"`
+	SuccessRedirectURL string `json:"success_redirect_url,omitempty"`
+	FailureRedirectURL string `json:"failure_redirect_url,omitempty"`
+}
+
+type xenditInvoice struct {
+	ExternalID string  `json:"external_id"`
+	Status     string  `json:"status"`
+	Amount     float64 `json:"amount"`
+	InvoiceURL string  `json:"invoice_url"`
+}
+
+func (p *xenditProvider) Name() string {
+	return "xendit"
+}
+
+func (p *xenditProvider) GetPaymentURL(transaction Transaction, user user.User) (string, error) {
+	body, err := json.Marshal(xenditInvoiceRequest{
+		ExternalID:         strconv.Itoa(transaction.ID),
+		Amount:             transaction.Amount,
+		PayerEmail:         user.Email,
+		Description:        "Pledge " + strconv.Itoa(transaction.ID),
+		Currency:           "IDR",
+		SuccessRedirectURL: p.config.SuccessURL,
+		FailureRedirectURL: p.config.FailureURL,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, p.endpoint+"/v2/invoices", bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+
+	req.SetBasicAuth(p.config.SecretKey, "")
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return "", fmt.Errorf("xendit create invoice: %s: %s", resp.Status, message)
+	}
+
+	var invoice xenditInvoice
+
+	err = json.NewDecoder(resp.Body).Decode(&invoice)
+	if err != nil {
+		return "", err
+	}
+
+	return invoice.InvoiceURL, nil
+}
+
+func (p *xenditProvider) ParseNotification(header http.Header, body []byte) (Notification, error) {
+	token := header.Get("X-Callback-Token")
+	if p.config.CallbackToken == "" || subtle.ConstantTimeCompare([]byte(token), []byte(p.config.CallbackToken)) != 1 {
+		return Notification{}, ErrInvalidSignature
+	}
+
+	var invoice xenditInvoice
+
+	err := json.Unmarshal(body, &invoice)
+	if err != nil {
+		return Notification{}, ErrInvalidPayload
+	}
+
+	notification := Notification{}
+	notification.OrderID = invoice.ExternalID
+	notification.ProviderStatus = invoice.Status
+	notification.Amount = int(invoice.Amount)
+
+	if invoice.Amount != float64(notification.Amount) {
+		return Notification{}, ErrInvalidPayload
+	}
+
+	switch strings.ToUpper(invoice.Status) {
+	case "PAID", "SETTLED":
+		notification.Status = StatusPaid
+	case "EXPIRED":
+		notification.Status = StatusFailed
+	}
+
+	return notification, nil
+}
@@ -0,0 +1,70 @@
+package payment
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestXenditParseNotification(t *testing.T) {
+	provider := NewXenditProvider(XenditConfig{CallbackToken: "callback-token"})
+
+	header := http.Header{}
+	header.Set("X-Callback-Token", "callback-token")
+
+	tests := []struct {
+		status string
+		want   string
+	}{
+		{"PAID", StatusPaid},
+		{"SETTLED", StatusPaid},
+		{"EXPIRED", StatusFailed},
+		{"PENDING", ""},
+	}
+
+	for _, test := range tests {
+		body := []byte(`{"external_id":"3","status":"` + test.status + `","amount":10000}`)
+
+		notification, err := provider.ParseNotification(header, body)
+		if err != nil {
+			t.Errorf("%s: %v", test.status, err)
+			continue
+		}
+
+		if notification.Status != test.want || notification.OrderID != "3" || notification.Amount != 10000 {
+			t.Errorf("%s: got %+v, want status %q", test.status, notification, test.want)
+		}
+	}
+}
+
+func TestXenditRejectsInvalidNotification(t *testing.T) {
+	body := []byte(`{"external_id":"3","status":"PAID","amount":10000}`)
+
+	wrongToken := http.Header{}
+	wrongToken.Set("X-Callback-Token", "guessed")
+
+	validToken := http.Header{}
+	validToken.Set("X-Callback-Token", "callback-token")
+
+	tests := []struct {
+		name          string
+		callbackToken string
+		header        http.Header
+		body          []byte
+		want          error
+	}{
+		{"missing token", "callback-token", http.Header{}, body, ErrInvalidSignature},
+		{"wrong token", "callback-token", wrongToken, body, ErrInvalidSignature},
+		{"no token configured", "", http.Header{}, body, ErrInvalidSignature},
+		{"fractional amount", "callback-token", validToken, []byte(`{"external_id":"3","status":"PAID","amount":10000.5}`), ErrInvalidPayload},
+		{"not json", "callback-token", validToken, []byte("external_id=3"), ErrInvalidPayload},
+	}
+
+	for _, test := range tests {
+		provider := NewXenditProvider(XenditConfig{CallbackToken: test.callbackToken})
+
+		_, err := provider.ParseNotification(test.header, test.body)
+		if err != test.want {
+			t.Errorf("%s: err = %v, want %v", test.name, err, test.want)
+		}
+	}
+}
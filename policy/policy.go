@@ -0,0 +1,25 @@
+package policy
+
+import (
+	"bwastartup/user"
+	"errors"
+)
+
+var (
+	ErrNotOwner = errors.New("not an owner of the resource")
+	ErrNotAdmin = errors.New("admin role is required")
+)
+
+func IsAdmin(actor user.User) bool {
+	return actor.Role == user.RoleAdmin
+}
+
+// CanManage is the single ownership rule shared by the services: the owner
+// of a resource may manage it, and so may any admin.
+func CanManage(actor user.User, ownerID int) bool {
+	if actor.ID == 0 {
+		return false
+	}
+
+	return actor.ID == ownerID || IsAdmin(actor)
+}
@@ -0,0 +1,63 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	gcs "cloud.google.com/go/storage"
+)
+
+type gcsStorage struct {
+	client *gcs.Client
+	bucket string
+}
+
+// NewGCSStorage uses the application default credentials of the
+// environment, as the avatar upload always did.
+func NewGCSStorage(ctx context.Context, bucket string) (*gcsStorage, error) {
+	client, err := gcs.NewClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return &gcsStorage{client, bucket}, nil
+}
+
+func (s *gcsStorage) Put(ctx context.Context, key string, r io.Reader, contentType string) (string, error) {
+	object := s.client.Bucket(s.bucket).Object(key)
+
+	w := object.NewWriter(ctx)
+	w.ContentType = contentType
+
+	_, err := io.Copy(w, r)
+	if err != nil {
+		w.Close()
+		return "", err
+	}
+
+	err = w.Close()
+	if err != nil {
+		return "", err
+	}
+
+	err = object.ACL().Set(ctx, gcs.AllUsers, gcs.RoleReader)
+	if err != nil {
+		return "", err
+	}
+
+	return s.URL(key), nil
+}
+
+func (s *gcsStorage) Delete(ctx context.Context, key string) error {
+	err := s.client.Bucket(s.bucket).Object(key).Delete(ctx)
+	if err != nil && err != gcs.ErrObjectNotExist {
+		return err
+	}
+
+	return nil
+}
+
+func (s *gcsStorage) URL(key string) string {
+	return fmt.Sprintf("https://storage.googleapis.com/%s/%s", s.bucket, key)
+}
@@ -0,0 +1,33 @@
+package storage
+
+import (
+	"context"
+	"io"
+	"net/url"
+	"strings"
+)
+
+// Storage keeps uploaded files under slash separated keys such as
+// "avatars/12-me.jpg" and serves them from a public URL.
+type Storage interface {
+	Put(ctx context.Context, key string, r io.Reader, contentType string) (string, error)
+	Delete(ctx context.Context, key string) error
+	URL(key string) string
+}
+
+// KeyFromURL reverses Storage.URL, for deleting files of which only the URL
+// was saved. It reports false for URLs the storage did not produce, such as
+// files uploaded while another backend was configured.
+func KeyFromURL(storage Storage, fileURL string) (string, bool) {
+	prefix := storage.URL("")
+	if !strings.HasPrefix(fileURL, prefix) {
+		return "", false
+	}
+
+	key, err := url.PathUnescape(strings.TrimPrefix(fileURL, prefix))
+	if err != nil || key == "" {
+		return "", false
+	}
+
+	return key, true
+}
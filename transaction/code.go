@@ -0,0 +1,99 @@
+package transaction
+
+import (
+	"crypto/rand"
+	"errors"
+	"math/big"
+	"strings"
+	"time"
+)
+
+// codeAlphabet is Crockford's base32: no I, L, O or U, so codes read out
+// over the phone or copied from a receipt are hard to get wrong.
+const codeAlphabet = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+const (
+	codePrefix       = "TRC"
+	codeRandomLength = 8
+)
+
+var ErrInvalidCode = errors.New("invalid transaction code")
+
+// GenerateCode returns a code like TRC-20230115-7KQ2M9XD-3: the UTC date the
+// transaction was made, random characters and a check character. The
+// random part makes collisions unlikely, the unique index on Code makes
+// them impossible.
+func GenerateCode(now time.Time) (string, error) {
+	random := make([]byte, codeRandomLength)
+	max := big.NewInt(int64(len(codeAlphabet)))
+
+	for i := range random {
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", err
+		}
+
+		random[i] = codeAlphabet[n.Int64()]
+	}
+
+	body := now.UTC().Format("20060102") + "-" + string(random)
+
+	return codePrefix + "-" + body + "-" + string(checkCharacter(body)), nil
+}
+
+// ValidCode checks the layout and the check character of code, so typos can
+// be rejected without a database lookup.
+func ValidCode(code string) bool {
+	parts := strings.Split(code, "-")
+	if len(parts) != 4 || parts[0] != codePrefix || len(parts[1]) != 8 || len(parts[2]) != codeRandomLength || len(parts[3]) != 1 {
+		return false
+	}
+
+	_, err := time.Parse("20060102", parts[1])
+	if err != nil {
+		return false
+	}
+
+	body := parts[1] + "-" + parts[2]
+	for _, c := range body {
+		if c != '-' && !strings.ContainsRune(codeAlphabet, c) {
+			return false
+		}
+	}
+
+	return parts[3][0] == checkCharacter(body)
+}
+
+// NormalizeCode accepts codes typed in lower case or with surrounding
+// spaces.
+func NormalizeCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+// checkCharacter computes the Luhn mod 32 check character of body, which
+// catches any single wrong character and most swapped neighbours. Dashes
+// are skipped.
+func checkCharacter(body string) byte {
+	factor := 2
+	sum := 0
+	n := len(codeAlphabet)
+
+	for i := len(body) - 1; i >= 0; i-- {
+		codePoint := strings.IndexByte(codeAlphabet, body[i])
+		if codePoint < 0 {
+			continue
+		}
+
+		addend := factor * codePoint
+		if factor == 2 {
+			factor = 1
+		} else {
+			factor = 2
+		}
+
+		sum += addend/n + addend%n
+	}
+
+	remainder := sum % n
+	return codeAlphabet[(n-remainder)%n]
+}
@@ -0,0 +1,76 @@
+package transaction
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCheckCharacter(t *testing.T) {
+	tests := []struct {
+		body string
+		want byte
+	}{
+		{"20230115-7KQ2M9XD", '3'},
+		{"00000000-00000000", '0'},
+	}
+
+	for _, test := range tests {
+		got := checkCharacter(test.body)
+		if got != test.want {
+			t.Errorf("checkCharacter(%q) = %q, want %q", test.body, got, test.want)
+		}
+	}
+}
+
+func TestValidCode(t *testing.T) {
+	tests := []struct {
+		name string
+		code string
+		want bool
+	}{
+		{"valid", "TRC-20230115-7KQ2M9XD-3", true},
+		{"wrong check character", "TRC-20230115-7KQ2M9XD-4", false},
+		{"single character typo", "TRC-20230115-7KQ2M9XE-3", false},
+		{"swapped neighbours", "TRC-20230115-K7Q2M9XD-3", false},
+		{"wrong prefix", "TRX-20230115-7KQ2M9XD-3", false},
+		{"invalid date", "TRC-20231315-7KQ2M9XD-3", false},
+		{"letter outside the alphabet", "TRC-20230115-7KQ2M9XU-3", false},
+		{"too short", "TRC-20230115-7KQ2M9X-3", false},
+		{"lower case", "trc-20230115-7kq2m9xd-3", false},
+		{"legacy code", "ORDER-0001", false},
+		{"empty", "", false},
+	}
+
+	for _, test := range tests {
+		got := ValidCode(test.code)
+		if got != test.want {
+			t.Errorf("%s: ValidCode(%q) = %v, want %v", test.name, test.code, got, test.want)
+		}
+	}
+}
+
+func TestGenerateCodeIsValid(t *testing.T) {
+	now := time.Date(2023, 1, 15, 23, 30, 0, 0, time.FixedZone("WIB", 7*60*60))
+
+	for i := 0; i < 100; i++ {
+		code, err := GenerateCode(now)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !ValidCode(code) {
+			t.Fatalf("GenerateCode returned invalid code %q", code)
+		}
+
+		if code[4:12] != "20230115" {
+			t.Fatalf("GenerateCode(%v) = %q, want the UTC date 20230115", now, code)
+		}
+	}
+}
+
+func TestNormalizeCode(t *testing.T) {
+	got := NormalizeCode("  trc-20230115-7kq2m9xd-3 ")
+	if got != "TRC-20230115-7KQ2M9XD-3" {
+		t.Errorf("NormalizeCode = %q", got)
+	}
+}
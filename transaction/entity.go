@@ -10,12 +10,34 @@ type Transaction struct {
 	ID         int
 	CampaignID int
 	UserID     int
+	RewardID   *int
 	Amount     int
 	Status     string
-	Code       string
+	Code       string `gorm:"uniqueIndex"`
 	PaymentURL string
 	User       user.User
 	Campaign   campaign.Campaign
 	CreatedAt  time.Time
 	UpdatedAt  time.Time
 }
+
+// PaymentNotification is one notification received from the payment
+// provider, kept whether or not it was applied.
+type PaymentNotification struct {
+	ID             int
+	TransactionID  int `gorm:"index"`
+	Provider       string
+	OrderID        string
+	ProviderStatus string
+	Amount         int
+	Payload        string
+	Result         string
+	Error          string
+	CreatedAt      time.Time
+}
+
+const (
+	NotificationApplied  = "applied"
+	NotificationIgnored  = "ignored"
+	NotificationRejected = "rejected"
+)
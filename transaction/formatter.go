@@ -40,6 +40,7 @@ type UserTransactionFormatter struct {
 	ID        int               `json:"id"`
 	Amount    int               `json:"amount"`
 	Status    string            `json:"status"`
+	RewardID  *int              `json:"reward_id"`
 	CreatedAt time.Time         `json:"created_at"`
 	Campaign  CampaignFormatter `json:"campaign"`
 }
@@ -55,6 +56,7 @@ func FormatUserTransaction(transaction Transaction) UserTransactionFormatter {
 	formatter.ID = transaction.ID
 	formatter.Amount = transaction.Amount
 	formatter.Status = transaction.Status
+	formatter.RewardID = transaction.RewardID
 	formatter.CreatedAt = transaction.CreatedAt
 
 	campaignFormatter := CampaignFormatter{}
@@ -89,6 +91,7 @@ type TransactionFormatter struct {
 	ID         int    `json:"id"`
 	CampaignID int    `json:"campaign_id"`
 	UserID     int    `json:"user_id"`
+	RewardID   *int   `json:"reward_id"`
 	Amount     int    `json:"amount"`
 	Status     string `json:"status"`
 	Code       string `json:"code"`
@@ -101,6 +104,7 @@ func FormatTransaction(transaction Transaction) TransactionFormatter {
 	formatter.ID = transaction.ID
 	formatter.CampaignID = transaction.CampaignID
 	formatter.UserID = transaction.UserID
+	formatter.RewardID = transaction.RewardID
 	formatter.Amount = transaction.Amount
 	formatter.Status = transaction.Status
 	formatter.Code = transaction.Code
@@ -108,3 +112,71 @@ func FormatTransaction(transaction Transaction) TransactionFormatter {
 
 	return formatter
 }
+
+type TransactionDetailFormatter struct {
+	ID           int       `json:"id"`
+	Code         string    `json:"code"`
+	CampaignID   int       `json:"campaign_id"`
+	CampaignName string    `json:"campaign_name"`
+	UserID       int       `json:"user_id"`
+	UserName     string    `json:"user_name"`
+	RewardID     *int      `json:"reward_id"`
+	Amount       int       `json:"amount"`
+	Status       string    `json:"status"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+func FormatTransactionDetail(transaction Transaction) TransactionDetailFormatter {
+	formatter := TransactionDetailFormatter{}
+
+	formatter.ID = transaction.ID
+	formatter.Code = transaction.Code
+	formatter.CampaignID = transaction.CampaignID
+	formatter.CampaignName = transaction.Campaign.Name
+	formatter.UserID = transaction.UserID
+	formatter.UserName = transaction.User.Name
+	formatter.RewardID = transaction.RewardID
+	formatter.Amount = transaction.Amount
+	formatter.Status = transaction.Status
+	formatter.CreatedAt = transaction.CreatedAt
+
+	return formatter
+}
+
+type AdminTransactionFormatter struct {
+	ID           int       `json:"id"`
+	CampaignID   int       `json:"campaign_id"`
+	CampaignName string    `json:"campaign_name"`
+	UserID       int       `json:"user_id"`
+	UserName     string    `json:"user_name"`
+	Amount       int       `json:"amount"`
+	Status       string    `json:"status"`
+	Code         string    `json:"code"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+func FormatAdminTransaction(transaction Transaction) AdminTransactionFormatter {
+	formatter := AdminTransactionFormatter{}
+
+	formatter.ID = transaction.ID
+	formatter.CampaignID = transaction.CampaignID
+	formatter.CampaignName = transaction.Campaign.Name
+	formatter.UserID = transaction.UserID
+	formatter.UserName = transaction.User.Name
+	formatter.Amount = transaction.Amount
+	formatter.Status = transaction.Status
+	formatter.Code = transaction.Code
+	formatter.CreatedAt = transaction.CreatedAt
+
+	return formatter
+}
+
+func FormatAdminTransactions(transactions []Transaction) []AdminTransactionFormatter {
+	transactionsFormatter := []AdminTransactionFormatter{}
+
+	for _, transaction := range transactions {
+		transactionsFormatter = append(transactionsFormatter, FormatAdminTransaction(transaction))
+	}
+
+	return transactionsFormatter
+}
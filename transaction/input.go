@@ -1,21 +1,30 @@
 package transaction
 
-import "bwastartup/user"
+import (
+	"bwastartup/user"
+	"net/http"
+)
 
 type GetCampaignTransactionsInput struct {
 	ID   int `uri:"id" binding:"required"`
 	User user.User
 }
 
+type GetTransactionByCodeInput struct {
+	Code string `uri:"code" binding:"required"`
+	User user.User
+}
+
 type CreateTransactionInput struct {
 	Amount     int `json:"amount" binding:"required"`
 	CampaignID int `json:"campaign_id" binding:"required"`
+	RewardID   int `json:"reward_id"`
 	User       user.User
 }
 
+// TransactionNotificationInput is a notification exactly as the payment
+// provider posted it. Each provider has its own format and signature.
 type TransactionNotificationInput struct {
-	TransactionStatus string `json:"transaction_status"`
-	OrderID           string `json:"order_id"`
-	PaymentType       string `json:"payment_type"`
-	FraudStatus       string `json:"fraud_status"`
+	Header http.Header
+	Body   []byte
 }
@@ -0,0 +1,36 @@
+package transaction
+
+import "gorm.io/gorm"
+
+// BackfillCodes gives every transaction without a valid code, such as the
+// ones all created with "TRC-0000101", a generated code dated on its
+// creation day. It has to run before AutoMigrate adds the unique index.
+func BackfillCodes(db *gorm.DB) error {
+	if !db.Migrator().HasTable(&Transaction{}) {
+		return nil
+	}
+
+	var transactions []Transaction
+	err := db.Select("id", "code", "created_at").Find(&transactions).Error
+	if err != nil {
+		return err
+	}
+
+	for _, transaction := range transactions {
+		if ValidCode(transaction.Code) {
+			continue
+		}
+
+		code, err := GenerateCode(transaction.CreatedAt)
+		if err != nil {
+			return err
+		}
+
+		err = db.Model(&Transaction{}).Where("id = ?", transaction.ID).Update("code", code).Error
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -1,23 +1,46 @@
 package transaction
 
-import "gorm.io/gorm"
+import (
+	"bwastartup/user"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
 
 type repository struct {
 	db *gorm.DB
 }
 
 type Repository interface {
+	FindAll() ([]Transaction, error)
 	GetByCampaignID(campaignID int) ([]Transaction, error)
 	GetByUserID(userID int) ([]Transaction, error)
 	GetByID(ID int) (Transaction, error)
+	GetByIDForUpdate(ID int) (Transaction, error)
+	GetByCode(code string) (Transaction, error)
 	Save(transaction Transaction) (Transaction, error)
 	Update(transaction Transaction) (Transaction, error)
+	UpdateStatus(ID int, from string, to string) (bool, error)
+	SaveNotification(notification PaymentNotification) (PaymentNotification, error)
+	IsBacker(campaignID int, userID int) (bool, error)
+	GetBackers(campaignID int) ([]user.User, error)
 }
 
 func NewRepository(db *gorm.DB) *repository {
 	return &repository{db}
 }
 
+func (r *repository) FindAll() ([]Transaction, error) {
+	var transactions []Transaction
+
+	err := r.db.Preload("User").Preload("Campaign").Order("id desc").Find(&transactions).Error
+	if err != nil {
+		return transactions, err
+	}
+
+	return transactions, nil
+}
+
 func (r *repository) GetByCampaignID(campaignID int) ([]Transaction, error) {
 	var transaction []Transaction
 	err := r.db.Where("campaign_id = ?", campaignID).Preload("User").Order("id desc").Find(&transaction).Error
@@ -48,8 +71,10 @@ func (r *repository) Save(transaction Transaction) (Transaction, error) {
 	return transaction, nil
 }
 
+// Update never writes the status, which only changes through UpdateStatus
+// while notifications may be arriving, nor the preloaded user and campaign.
 func (r *repository) Update(transaction Transaction) (Transaction, error) {
-	err := r.db.Save(&transaction).Error
+	err := r.db.Omit("status", clause.Associations).Save(&transaction).Error
 	if err != nil {
 		return transaction, err
 	}
@@ -57,6 +82,26 @@ func (r *repository) Update(transaction Transaction) (Transaction, error) {
 	return transaction, nil
 }
 
+// UpdateStatus moves the transaction to the new status only if it still has
+// the old one, and reports whether it did.
+func (r *repository) UpdateStatus(ID int, from string, to string) (bool, error) {
+	result := r.db.Model(&Transaction{}).Where("id = ? AND status = ?", ID, from).Update("status", to)
+	if result.Error != nil {
+		return false, result.Error
+	}
+
+	return result.RowsAffected == 1, nil
+}
+
+func (r *repository) SaveNotification(notification PaymentNotification) (PaymentNotification, error) {
+	err := r.db.Create(&notification).Error
+	if err != nil {
+		return notification, err
+	}
+
+	return notification, nil
+}
+
 func (r *repository) GetByID(ID int) (Transaction, error) {
 	var transaction Transaction
 
@@ -67,3 +112,53 @@ func (r *repository) GetByID(ID int) (Transaction, error) {
 
 	return transaction, nil
 }
+
+// GetByIDForUpdate locks the transaction row until the surrounding database
+// transaction ends. It only makes sense inside a UnitOfWork.
+func (r *repository) GetByIDForUpdate(ID int) (Transaction, error) {
+	var transaction Transaction
+
+	err := r.db.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", ID).Find(&transaction).Error
+	if err != nil {
+		return transaction, err
+	}
+
+	return transaction, nil
+}
+
+func (r *repository) IsBacker(campaignID int, userID int) (bool, error) {
+	var count int64
+
+	err := r.db.Model(&Transaction{}).Where("campaign_id = ? AND user_id = ? AND status = ?", campaignID, userID, StatusPaid).Count(&count).Error
+	if err != nil {
+		return false, err
+	}
+
+	return count > 0, nil
+}
+
+// GetBackers returns every user with a paid transaction on the campaign,
+// once each.
+func (r *repository) GetBackers(campaignID int) ([]user.User, error) {
+	var backers []user.User
+
+	backerIDs := r.db.Model(&Transaction{}).Select("user_id").Where("campaign_id = ? AND status = ?", campaignID, StatusPaid)
+
+	err := r.db.Where("id IN (?)", backerIDs).Find(&backers).Error
+	if err != nil {
+		return backers, err
+	}
+
+	return backers, nil
+}
+
+func (r *repository) GetByCode(code string) (Transaction, error) {
+	var transaction Transaction
+
+	err := r.db.Where("code = ?", code).Preload("User").Preload("Campaign").Find(&transaction).Error
+	if err != nil {
+		return transaction, err
+	}
+
+	return transaction, nil
+}
@@ -2,26 +2,59 @@ package transaction
 
 import (
 	"bwastartup/campaign"
+	"bwastartup/helper"
 	"bwastartup/payment"
+	"bwastartup/policy"
+	"bwastartup/user"
 	"errors"
+	"log"
 	"strconv"
+	"time"
+)
+
+// maxCodeAttempts bounds the retries when a generated code already exists.
+const maxCodeAttempts = 5
+
+var (
+	ErrTransactionNotFound = errors.New("transaction not found")
+	ErrCampaignNotOpen     = errors.New("campaign is not accepting pledges")
+	ErrRewardNotFound      = errors.New("reward not found")
+	ErrRewardSoldOut       = errors.New("reward is sold out")
+	ErrAmountBelowMinimum  = errors.New("amount is below the minimum pledge of the reward")
+	ErrAmountMismatch      = errors.New("notification amount does not match the transaction")
 )
 
 type service struct {
 	repository         Repository
 	campaignRepository campaign.Repository
 	paymentService     payment.Service
+	unitOfWork         UnitOfWork
 }
 
 type Service interface {
+	GetTransactions(currentUser user.User) ([]Transaction, error)
 	GetTransactionByCampaignID(input GetCampaignTransactionsInput) ([]Transaction, error)
 	GetTransactionByUserID(userID int) ([]Transaction, error)
+	GetTransactionByCode(input GetTransactionByCodeInput) (Transaction, error)
 	CreateTransaction(input CreateTransactionInput) (Transaction, error)
 	ProcessPayment(input TransactionNotificationInput) error
 }
 
-func NewService(repository Repository, campaignRepository campaign.Repository, paymentService payment.Service) *service {
-	return &service{repository, campaignRepository, paymentService}
+func NewService(repository Repository, campaignRepository campaign.Repository, paymentService payment.Service, unitOfWork UnitOfWork) *service {
+	return &service{repository, campaignRepository, paymentService, unitOfWork}
+}
+
+func (s *service) GetTransactions(currentUser user.User) ([]Transaction, error) {
+	if !policy.IsAdmin(currentUser) {
+		return []Transaction{}, policy.ErrNotAdmin
+	}
+
+	transactions, err := s.repository.FindAll()
+	if err != nil {
+		return transactions, err
+	}
+
+	return transactions, nil
 }
 
 func (s *service) GetTransactionByCampaignID(input GetCampaignTransactionsInput) ([]Transaction, error) {
@@ -30,8 +63,8 @@ func (s *service) GetTransactionByCampaignID(input GetCampaignTransactionsInput)
 		return []Transaction{}, err
 	}
 
-	if campaign.UserID != input.User.ID {
-		return []Transaction{}, errors.New("not an owner of the campaign")
+	if !policy.CanManage(input.User, campaign.UserID) {
+		return []Transaction{}, policy.ErrNotOwner
 	}
 
 	transaction, err := s.repository.GetByCampaignID(input.ID)
@@ -51,16 +84,74 @@ func (s *service) GetTransactionByUserID(userID int) ([]Transaction, error) {
 	return transaction, nil
 }
 
+// GetTransactionByCode is for support and receipts, so besides the backer
+// the campaign owner and admins can look transactions up too.
+func (s *service) GetTransactionByCode(input GetTransactionByCodeInput) (Transaction, error) {
+	code := NormalizeCode(input.Code)
+	if !ValidCode(code) {
+		return Transaction{}, ErrInvalidCode
+	}
+
+	transaction, err := s.repository.GetByCode(code)
+	if err != nil {
+		return transaction, err
+	}
+
+	if transaction.ID == 0 {
+		return Transaction{}, ErrTransactionNotFound
+	}
+
+	if transaction.UserID != input.User.ID && !policy.CanManage(input.User, transaction.Campaign.UserID) {
+		return Transaction{}, policy.ErrNotOwner
+	}
+
+	return transaction, nil
+}
+
 func (s *service) CreateTransaction(input CreateTransactionInput) (Transaction, error) {
+	campaign, err := s.campaignRepository.FindByID(input.CampaignID)
+	if err != nil {
+		return Transaction{}, err
+	}
+
+	if campaign.ID == 0 || !campaign.AcceptsPledges(time.Now()) {
+		return Transaction{}, ErrCampaignNotOpen
+	}
+
 	transaction := Transaction{}
 	transaction.CampaignID = input.CampaignID
 	transaction.Amount = input.Amount
 	transaction.UserID = input.User.ID
-	transaction.Status = "pending"
-	transaction.Code = "TRC-0000101"
+	transaction.Status = StatusPending
+
+	if input.RewardID != 0 {
+		err := s.reserveReward(campaign, input.RewardID, input.Amount)
+		if err != nil {
+			return Transaction{}, err
+		}
+
+		transaction.RewardID = &input.RewardID
+	}
+
+	var newTransaction Transaction
+	for attempt := 0; attempt < maxCodeAttempts; attempt++ {
+		transaction.Code, err = GenerateCode(time.Now())
+		if err != nil {
+			break
+		}
+
+		newTransaction, err = s.repository.Save(transaction)
+		if !helper.IsUniqueViolation(err) {
+			break
+		}
+	}
 
-	newTransaction, err := s.repository.Save(transaction)
 	if err != nil {
+		releaseErr := releaseReward(s.campaignRepository, transaction)
+		if releaseErr != nil {
+			log.Println("release reward of unsaved transaction:", releaseErr.Error())
+		}
+
 		return newTransaction, err
 	}
 
@@ -71,6 +162,22 @@ func (s *service) CreateTransaction(input CreateTransactionInput) (Transaction,
 
 	paymentURL, err := s.paymentService.GetPaymentURL(paymentTransaction, input.User)
 	if err != nil {
+		// Nobody can pay for it, so give the reward back right away. The
+		// payment error is the one returned, cleanup failures are logged.
+		cancelled, cancelErr := s.repository.UpdateStatus(newTransaction.ID, StatusPending, StatusCancelled)
+		if cancelErr != nil {
+			log.Println("cancel transaction", newTransaction.ID, "without payment URL:", cancelErr.Error())
+		}
+
+		if cancelled {
+			newTransaction.Status = StatusCancelled
+
+			releaseErr := releaseReward(s.campaignRepository, newTransaction)
+			if releaseErr != nil {
+				log.Println("release reward of transaction", newTransaction.ID, "without payment URL:", releaseErr.Error())
+			}
+		}
+
 		return newTransaction, err
 	}
 
@@ -84,40 +191,144 @@ func (s *service) CreateTransaction(input CreateTransactionInput) (Transaction,
 	return newTransaction, nil
 }
 
+// ProcessPayment applies a payment provider notification and records it in
+// the notification history, whatever the outcome.
 func (s *service) ProcessPayment(input TransactionNotificationInput) error {
-	transaction_id, _ := strconv.Atoi(input.OrderID)
+	record := PaymentNotification{}
+	record.Provider = s.paymentService.Name()
+	record.Payload = string(input.Body)
+	record.Result = NotificationIgnored
+
+	notification, err := s.paymentService.ParseNotification(input.Header, input.Body)
+
+	applied := false
+	if err == nil {
+		record.TransactionID, _ = strconv.Atoi(notification.OrderID)
+		record.OrderID = notification.OrderID
+		record.ProviderStatus = notification.ProviderStatus
+		record.Amount = notification.Amount
+
+		applied, err = s.applyNotification(notification)
+	}
 
-	transaction, err := s.repository.GetByID(transaction_id)
+	if err != nil {
+		record.Result = NotificationRejected
+		record.Error = err.Error()
+	} else if applied {
+		record.Result = NotificationApplied
+	}
+
+	_, saveErr := s.repository.SaveNotification(record)
 	if err != nil {
 		return err
 	}
 
-	if input.PaymentType == "credit_card" && input.TransactionStatus == "capture" && input.FraudStatus == "accept" {
-		transaction.Status = "paid"
-	} else if input.TransactionStatus == "settlement" {
-		transaction.Status = "paid"
-	} else if input.TransactionStatus == "deny" || input.TransactionStatus == "expire" || input.TransactionStatus == "cancel" {
-		transaction.Status = "cancelled"
+	return saveErr
+}
+
+// applyNotification moves the transaction to the status the notification
+// asks for and reports whether it did. The transaction row stays locked
+// until the status and the campaign counters are written together, so
+// repeated or concurrent notifications only count a payment once.
+func (s *service) applyNotification(notification payment.Notification) (bool, error) {
+	transaction_id, _ := strconv.Atoi(notification.OrderID)
+
+	applied := false
+
+	err := s.unitOfWork.Do(func(repository Repository, campaignRepository campaign.Repository) error {
+		transaction, err := repository.GetByIDForUpdate(transaction_id)
+		if err != nil {
+			return err
+		}
+
+		if transaction.ID == 0 {
+			return ErrTransactionNotFound
+		}
+
+		if notification.Amount != transaction.Amount {
+			return ErrAmountMismatch
+		}
+
+		status := notificationStatus(notification)
+		if status == "" || status == transaction.Status {
+			return nil
+		}
+
+		// Gateways retry until they get a 2xx, so a genuine notification that
+		// arrives too late, like expire after settlement, is recorded as
+		// ignored instead of rejected.
+		if !canTransition(transaction.Status, status) {
+			return nil
+		}
+
+		_, err = repository.UpdateStatus(transaction.ID, transaction.Status, status)
+		if err != nil {
+			return err
+		}
+
+		transaction.Status = status
+
+		switch status {
+		case StatusPaid:
+			err = campaignRepository.IncrementFunding(transaction.CampaignID, 1, transaction.Amount)
+		case StatusCancelled:
+			err = releaseReward(campaignRepository, transaction)
+		case StatusRefunded:
+			err = campaignRepository.IncrementFunding(transaction.CampaignID, -1, -transaction.Amount)
+			if err == nil {
+				err = releaseReward(campaignRepository, transaction)
+			}
+		}
+
+		if err != nil {
+			return err
+		}
+
+		applied = true
+
+		return nil
+	})
+	if err != nil {
+		return false, err
 	}
 
-	updatedTransaction, err := s.repository.Update(transaction)
+	return applied, nil
+}
+
+func (s *service) reserveReward(pledgedCampaign campaign.Campaign, rewardID int, amount int) error {
+	reward, err := s.campaignRepository.FindRewardByID(rewardID)
 	if err != nil {
 		return err
 	}
 
-	campaign, err := s.campaignRepository.FindByID(updatedTransaction.CampaignID)
+	if reward.ID == 0 || reward.CampaignID != pledgedCampaign.ID {
+		return ErrRewardNotFound
+	}
+
+	if amount < reward.MinimumPledge {
+		return ErrAmountBelowMinimum
+	}
+
+	reserved, err := s.campaignRepository.ReserveReward(reward.ID)
 	if err != nil {
 		return err
 	}
 
-	if updatedTransaction.Status == "paid" {
-		campaign.BackerCount = campaign.BackerCount + 1
-		campaign.CurrentAmount = campaign.CurrentAmount + updatedTransaction.Amount
+	if !reserved {
+		return ErrRewardSoldOut
+	}
 
-		_, err := s.campaignRepository.Update(campaign)
-		if err != nil {
-			return err
-		}
+	return nil
+}
+
+func releaseReward(campaignRepository campaign.Repository, transaction Transaction) error {
+	if transaction.RewardID == nil {
+		return nil
+	}
+
+	_, err := campaignRepository.ReleaseReward(*transaction.RewardID)
+	if err != nil {
+		return err
 	}
 
 	return nil
@@ -0,0 +1,186 @@
+package transaction
+
+import (
+	"bwastartup/campaign"
+	"bwastartup/payment"
+	"bwastartup/user"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// memoryRepository keeps transactions and notifications in memory. Methods
+// the pledge flow does not use are left to the embedded nil interface.
+type memoryRepository struct {
+	Repository
+	transactions  map[int]Transaction
+	notifications []PaymentNotification
+}
+
+func (r *memoryRepository) Save(transaction Transaction) (Transaction, error) {
+	transaction.ID = len(r.transactions) + 1
+	r.transactions[transaction.ID] = transaction
+
+	return transaction, nil
+}
+
+func (r *memoryRepository) Update(transaction Transaction) (Transaction, error) {
+	transaction.Status = r.transactions[transaction.ID].Status
+	r.transactions[transaction.ID] = transaction
+
+	return transaction, nil
+}
+
+func (r *memoryRepository) GetByIDForUpdate(ID int) (Transaction, error) {
+	return r.transactions[ID], nil
+}
+
+func (r *memoryRepository) UpdateStatus(ID int, from string, to string) (bool, error) {
+	transaction, ok := r.transactions[ID]
+	if !ok || transaction.Status != from {
+		return false, nil
+	}
+
+	transaction.Status = to
+	r.transactions[ID] = transaction
+
+	return true, nil
+}
+
+func (r *memoryRepository) SaveNotification(notification PaymentNotification) (PaymentNotification, error) {
+	r.notifications = append(r.notifications, notification)
+
+	return notification, nil
+}
+
+type memoryCampaignRepository struct {
+	campaign.Repository
+	campaign campaign.Campaign
+}
+
+func (r *memoryCampaignRepository) FindByID(ID int) (campaign.Campaign, error) {
+	if ID != r.campaign.ID {
+		return campaign.Campaign{}, nil
+	}
+
+	return r.campaign, nil
+}
+
+func (r *memoryCampaignRepository) IncrementFunding(ID int, backers int, amount int) error {
+	r.campaign.BackerCount += backers
+	r.campaign.CurrentAmount += amount
+
+	return nil
+}
+
+type memoryUnitOfWork struct {
+	repository         Repository
+	campaignRepository campaign.Repository
+}
+
+func (u memoryUnitOfWork) Do(fn func(repository Repository, campaignRepository campaign.Repository) error) error {
+	return fn(u.repository, u.campaignRepository)
+}
+
+// TestPledgeThroughFakeProvider runs a pledge from CreateTransaction to the
+// notification the fake provider posts back, the way the API wires them.
+func TestPledgeThroughFakeProvider(t *testing.T) {
+	deadline := time.Now().Add(24 * time.Hour)
+
+	repository := &memoryRepository{transactions: map[int]Transaction{}}
+	campaignRepository := &memoryCampaignRepository{campaign: campaign.Campaign{ID: 5, UserID: 1, Status: campaign.StatusPublished, Deadline: &deadline}}
+
+	var service *service
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+
+		err := service.ProcessPayment(TransactionNotificationInput{Header: r.Header, Body: body})
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		}
+	}))
+	defer server.Close()
+
+	provider := payment.NewFakeProvider(payment.FakeConfig{CheckoutURL: "http://api.test/payments/fake", NotificationURL: server.URL, Secret: "secret"})
+	service = NewService(repository, campaignRepository, provider, memoryUnitOfWork{repository, campaignRepository})
+
+	backer := user.User{ID: 2, Name: "Backer", Email: "backer@example.com"}
+
+	transaction, err := service.CreateTransaction(CreateTransactionInput{Amount: 5000, CampaignID: 5, User: backer})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if transaction.Status != StatusPending || !ValidCode(transaction.Code) {
+		t.Fatalf("CreateTransaction returned %+v", transaction)
+	}
+
+	paymentURL, err := url.Parse(transaction.PaymentURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	query := paymentURL.Query()
+	amount, _ := strconv.Atoi(query.Get("amount"))
+	checkout := payment.FakeCheckoutInput{OrderID: query.Get("order_id"), Amount: amount, Signature: query.Get("signature")}
+
+	// The backer pays, the notification is delivered twice and then a late
+	// failure arrives. Only the first one may count.
+	for _, status := range []string{payment.StatusPaid, payment.StatusPaid, payment.StatusFailed} {
+		checkout.Status = status
+
+		err = provider.Complete(checkout)
+		if err != nil {
+			t.Fatalf("Complete(%s): %v", status, err)
+		}
+	}
+
+	if got := repository.transactions[transaction.ID].Status; got != StatusPaid {
+		t.Errorf("transaction status = %q, want %q", got, StatusPaid)
+	}
+
+	if campaignRepository.campaign.BackerCount != 1 || campaignRepository.campaign.CurrentAmount != 5000 {
+		t.Errorf("campaign counters = %d backers, %d amount, want 1 and 5000", campaignRepository.campaign.BackerCount, campaignRepository.campaign.CurrentAmount)
+	}
+
+	wantResults := []string{NotificationApplied, NotificationIgnored, NotificationIgnored}
+	if len(repository.notifications) != len(wantResults) {
+		t.Fatalf("recorded %d notifications, want %d", len(repository.notifications), len(wantResults))
+	}
+
+	for i, notification := range repository.notifications {
+		if notification.Result != wantResults[i] || notification.TransactionID != transaction.ID || notification.Provider != "fake" {
+			t.Errorf("notification %d = %+v, want result %q", i, notification, wantResults[i])
+		}
+	}
+}
+
+func TestProcessPaymentRejectsInvalidNotifications(t *testing.T) {
+	repository := &memoryRepository{transactions: map[int]Transaction{
+		1: {ID: 1, CampaignID: 5, Amount: 5000, Status: StatusPending},
+	}}
+	campaignRepository := &memoryCampaignRepository{campaign: campaign.Campaign{ID: 5}}
+
+	provider := payment.NewFakeProvider(payment.FakeConfig{Secret: "secret"})
+	service := NewService(repository, campaignRepository, provider, memoryUnitOfWork{repository, campaignRepository})
+
+	forged := []byte(`{"order_id":"1","status":"paid","amount":5000,"signature":"forged"}`)
+
+	err := service.ProcessPayment(TransactionNotificationInput{Header: http.Header{}, Body: forged})
+	if err != payment.ErrInvalidSignature {
+		t.Errorf("forged notification: err = %v, want ErrInvalidSignature", err)
+	}
+
+	if repository.transactions[1].Status != StatusPending || campaignRepository.campaign.CurrentAmount != 0 {
+		t.Errorf("forged notification changed the transaction or the campaign")
+	}
+
+	if len(repository.notifications) != 1 || repository.notifications[0].Result != NotificationRejected {
+		t.Errorf("forged notification recorded as %+v, want one rejected", repository.notifications)
+	}
+}
@@ -0,0 +1,43 @@
+package transaction
+
+import "bwastartup/payment"
+
+const (
+	StatusPending   = "pending"
+	StatusPaid      = "paid"
+	StatusCancelled = "cancelled"
+	StatusRefunded  = "refunded"
+)
+
+// transitions lists the statuses each status can move to. Paid and cancelled
+// are final apart from a refund of a paid transaction.
+var transitions = map[string][]string{
+	StatusPending: {StatusPaid, StatusCancelled},
+	StatusPaid:    {StatusRefunded},
+}
+
+func canTransition(from string, to string) bool {
+	for _, status := range transitions[from] {
+		if status == to {
+			return true
+		}
+	}
+
+	return false
+}
+
+// notificationStatus maps the outcome a payment provider reports to the
+// status it asks for. Notifications that change nothing map to an empty
+// string.
+func notificationStatus(notification payment.Notification) string {
+	switch notification.Status {
+	case payment.StatusPaid:
+		return StatusPaid
+	case payment.StatusFailed:
+		return StatusCancelled
+	case payment.StatusRefunded:
+		return StatusRefunded
+	}
+
+	return ""
+}
@@ -0,0 +1,52 @@
+package transaction
+
+import (
+	"bwastartup/payment"
+	"testing"
+)
+
+func TestCanTransition(t *testing.T) {
+	tests := []struct {
+		from string
+		to   string
+		want bool
+	}{
+		{StatusPending, StatusPaid, true},
+		{StatusPending, StatusCancelled, true},
+		{StatusPending, StatusRefunded, false},
+		{StatusPending, StatusPending, false},
+		{StatusPaid, StatusRefunded, true},
+		{StatusPaid, StatusCancelled, false},
+		{StatusPaid, StatusPending, false},
+		{StatusCancelled, StatusPaid, false},
+		{StatusCancelled, StatusRefunded, false},
+		{StatusRefunded, StatusPaid, false},
+		{"unknown", StatusPaid, false},
+	}
+
+	for _, test := range tests {
+		got := canTransition(test.from, test.to)
+		if got != test.want {
+			t.Errorf("canTransition(%q, %q) = %v, want %v", test.from, test.to, got, test.want)
+		}
+	}
+}
+
+func TestNotificationStatus(t *testing.T) {
+	tests := []struct {
+		status string
+		want   string
+	}{
+		{payment.StatusPaid, StatusPaid},
+		{payment.StatusFailed, StatusCancelled},
+		{payment.StatusRefunded, StatusRefunded},
+		{"", ""},
+	}
+
+	for _, test := range tests {
+		got := notificationStatus(payment.Notification{Status: test.status})
+		if got != test.want {
+			t.Errorf("notificationStatus(%q) = %q, want %q", test.status, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,28 @@
+package transaction
+
+import (
+	"bwastartup/campaign"
+
+	"gorm.io/gorm"
+)
+
+// UnitOfWork runs fn inside one database transaction, with repositories
+// bound to it. Everything fn writes is committed together, or rolled back
+// when it returns an error.
+type UnitOfWork interface {
+	Do(fn func(repository Repository, campaignRepository campaign.Repository) error) error
+}
+
+type unitOfWork struct {
+	db *gorm.DB
+}
+
+func NewUnitOfWork(db *gorm.DB) *unitOfWork {
+	return &unitOfWork{db}
+}
+
+func (u *unitOfWork) Do(fn func(repository Repository, campaignRepository campaign.Repository) error) error {
+	return u.db.Transaction(func(tx *gorm.DB) error {
+		return fn(NewRepository(tx), campaign.NewRepository(tx))
+	})
+}
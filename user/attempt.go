@@ -0,0 +1,174 @@
+package user
+
+import (
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// AttemptStore counts failed logins per identifier, which is either an
+// account ("email:...") or a client address ("ip:..."). RecordFailure must be
+// atomic and returns the number of failures within the last window, so old
+// failures expire one by one instead of the whole count resetting after a
+// quiet period.
+type AttemptStore interface {
+	Get(identifier string) (LoginAttempt, error)
+	RecordFailure(identifier string, window time.Duration) (LoginAttempt, error)
+	Lock(identifier string, until time.Time) error
+	Reset(identifier string) error
+}
+
+type memoryAttemptStore struct {
+	mu       sync.Mutex
+	attempts map[string]LoginAttempt
+	failures map[string][]time.Time
+}
+
+func NewMemoryAttemptStore() *memoryAttemptStore {
+	return &memoryAttemptStore{attempts: map[string]LoginAttempt{}, failures: map[string][]time.Time{}}
+}
+
+func (s *memoryAttemptStore) Get(identifier string) (LoginAttempt, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.attempts[identifier], nil
+}
+
+func (s *memoryAttemptStore) RecordFailure(identifier string, window time.Duration) (LoginAttempt, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	for key, failedAts := range s.failures {
+		var recent []time.Time
+		for _, failedAt := range failedAts {
+			if now.Sub(failedAt) < window {
+				recent = append(recent, failedAt)
+			}
+		}
+		s.failures[key] = recent
+
+		attempt := s.attempts[key]
+		isLocked := attempt.LockedUntil != nil && attempt.LockedUntil.After(now)
+		if len(recent) == 0 && !isLocked {
+			delete(s.failures, key)
+			delete(s.attempts, key)
+		}
+	}
+
+	s.failures[identifier] = append(s.failures[identifier], now)
+
+	attempt := s.attempts[identifier]
+	attempt.Identifier = identifier
+	attempt.Failures = len(s.failures[identifier])
+	attempt.LastFailedAt = now
+	s.attempts[identifier] = attempt
+
+	return attempt, nil
+}
+
+func (s *memoryAttemptStore) Lock(identifier string, until time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	attempt := s.attempts[identifier]
+	attempt.Identifier = identifier
+	attempt.LockedUntil = &until
+	s.attempts[identifier] = attempt
+
+	return nil
+}
+
+func (s *memoryAttemptStore) Reset(identifier string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.attempts, identifier)
+	delete(s.failures, identifier)
+	return nil
+}
+
+type postgresAttemptStore struct {
+	db *gorm.DB
+}
+
+func NewPostgresAttemptStore(db *gorm.DB) *postgresAttemptStore {
+	return &postgresAttemptStore{db}
+}
+
+func (s *postgresAttemptStore) Get(identifier string) (LoginAttempt, error) {
+	var attempt LoginAttempt
+
+	err := s.db.Where("identifier = ?", identifier).Find(&attempt).Error
+	if err != nil {
+		return attempt, err
+	}
+
+	return attempt, nil
+}
+
+func (s *postgresAttemptStore) RecordFailure(identifier string, window time.Duration) (LoginAttempt, error) {
+	var attempt LoginAttempt
+
+	now := time.Now()
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		// The upsert locks the identifier's row until the end of the
+		// transaction, so concurrent failures are counted one after another.
+		err := tx.Exec(`INSERT INTO login_attempts (identifier, failures, last_failed_at, updated_at)
+			VALUES (?, 0, ?, ?)
+			ON CONFLICT (identifier) DO UPDATE SET
+				last_failed_at = EXCLUDED.last_failed_at,
+				updated_at = EXCLUDED.updated_at`, identifier, now, now).Error
+		if err != nil {
+			return err
+		}
+
+		err = tx.Where("identifier = ? AND failed_at <= ?", identifier, now.Add(-window)).Delete(&LoginFailure{}).Error
+		if err != nil {
+			return err
+		}
+
+		err = tx.Create(&LoginFailure{Identifier: identifier, FailedAt: now}).Error
+		if err != nil {
+			return err
+		}
+
+		var failures int64
+		err = tx.Model(&LoginFailure{}).Where("identifier = ?", identifier).Count(&failures).Error
+		if err != nil {
+			return err
+		}
+
+		return tx.Raw("UPDATE login_attempts SET failures = ? WHERE identifier = ? RETURNING *", failures, identifier).Scan(&attempt).Error
+	})
+	if err != nil {
+		return attempt, err
+	}
+
+	return attempt, nil
+}
+
+func (s *postgresAttemptStore) Lock(identifier string, until time.Time) error {
+	err := s.db.Model(&LoginAttempt{}).Where("identifier = ?", identifier).Update("locked_until", until).Error
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (s *postgresAttemptStore) Reset(identifier string) error {
+	err := s.db.Where("identifier = ?", identifier).Delete(&LoginFailure{}).Error
+	if err != nil {
+		return err
+	}
+
+	err = s.db.Where("identifier = ?", identifier).Delete(&LoginAttempt{}).Error
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -2,6 +2,11 @@ package user
 
 import "time"
 
+const (
+	RoleUser  = "user"
+	RoleAdmin = "admin"
+)
+
 type User struct {
 	ID             int
 	Name           string
@@ -10,6 +15,42 @@ type User struct {
 	PasswordHash   string
 	AvatarFileName string
 	Role           string
+	VerifiedAt     *time.Time
+	BannedAt       *time.Time
 	CreatedAt      time.Time
 	UpdatedAt      time.Time
 }
+
+const (
+	PurposePasswordReset     = "password_reset"
+	PurposeEmailVerification = "email_verification"
+)
+
+// ActionToken is a single-use token mailed to a user, e.g. to reset a
+// password or to verify an email address. Only the hash of the token is stored.
+type ActionToken struct {
+	ID        int
+	UserID    int
+	Purpose   string
+	TokenHash string `gorm:"uniqueIndex"`
+	ExpiresAt time.Time
+	UsedAt    *time.Time
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// LoginAttempt holds the lockout of an identifier. Failures is the number of
+// LoginFailure rows inside the window at the time of the last failure.
+type LoginAttempt struct {
+	Identifier   string `gorm:"primaryKey"`
+	Failures     int
+	LastFailedAt time.Time
+	LockedUntil  *time.Time
+	UpdatedAt    time.Time
+}
+
+type LoginFailure struct {
+	ID         int
+	Identifier string `gorm:"index"`
+	FailedAt   time.Time
+}
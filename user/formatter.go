@@ -1,12 +1,16 @@
 package user
 
+import "time"
+
 type UserFormatter struct {
-	ID         int    `json:"id"`
-	Name       string `json:"name"`
-	Email      string `json:"email"`
-	Occupation string `json:"occupation"`
-	Token      string `json:"token"`
-	ImageURL   string `json:"image_url"`
+	ID           int    `json:"id"`
+	Name         string `json:"name"`
+	Email        string `json:"email"`
+	Occupation   string `json:"occupation"`
+	Token        string `json:"token"`
+	RefreshToken string `json:"refresh_token,omitempty"`
+	ImageURL     string `json:"image_url"`
+	IsVerified   bool   `json:"is_verified"`
 }
 
 func FormatUser(user User, token string) UserFormatter {
@@ -17,6 +21,44 @@ func FormatUser(user User, token string) UserFormatter {
 		Occupation: user.Occupation,
 		Token:      token,
 		ImageURL:   user.AvatarFileName,
+		IsVerified: user.VerifiedAt != nil,
 	}
 	return formatter
 }
+
+type AdminUserFormatter struct {
+	ID         int        `json:"id"`
+	Name       string     `json:"name"`
+	Email      string     `json:"email"`
+	Occupation string     `json:"occupation"`
+	Role       string     `json:"role"`
+	ImageURL   string     `json:"image_url"`
+	IsVerified bool       `json:"is_verified"`
+	BannedAt   *time.Time `json:"banned_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+func FormatAdminUser(user User) AdminUserFormatter {
+	formatter := AdminUserFormatter{
+		ID:         user.ID,
+		Name:       user.Name,
+		Email:      user.Email,
+		Occupation: user.Occupation,
+		Role:       user.Role,
+		ImageURL:   user.AvatarFileName,
+		IsVerified: user.VerifiedAt != nil,
+		BannedAt:   user.BannedAt,
+		CreatedAt:  user.CreatedAt,
+	}
+	return formatter
+}
+
+func FormatAdminUsers(users []User) []AdminUserFormatter {
+	usersFormatter := []AdminUserFormatter{}
+
+	for _, user := range users {
+		usersFormatter = append(usersFormatter, FormatAdminUser(user))
+	}
+
+	return usersFormatter
+}
@@ -0,0 +1,138 @@
+package user
+
+import (
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+var testArgon2Params = Argon2Params{Memory: 1024, Iterations: 1, Parallelism: 1, SaltLength: 16, KeyLength: 32}
+
+func newTestHasherChain() *hasherChain {
+	return NewHasherChain(NewArgon2idHasher(testArgon2Params), NewBcryptHasher(bcrypt.MinCost))
+}
+
+func mustHash(t *testing.T, hasher PasswordHasher, password string) string {
+	hash, err := hasher.Hash(password)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return hash
+}
+
+func TestHasherChainVerify(t *testing.T) {
+	chain := newTestHasherChain()
+	argon2Hash := mustHash(t, chain, "secret password")
+	bcryptHash := mustHash(t, NewBcryptHasher(bcrypt.MinCost), "secret password")
+
+	tests := []struct {
+		name     string
+		hash     string
+		password string
+		want     bool
+		wantErr  error
+	}{
+		{"preferred hash, right password", argon2Hash, "secret password", true, nil},
+		{"preferred hash, wrong password", argon2Hash, "wrong password", false, nil},
+		{"legacy hash, right password", bcryptHash, "secret password", true, nil},
+		{"legacy hash, wrong password", bcryptHash, "wrong password", false, nil},
+		{"unknown format", "plain text", "plain text", false, ErrUnknownHashFormat},
+		{"malformed argon2id hash", "$argon2id$v=19$broken", "secret password", false, ErrUnknownHashFormat},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := chain.Verify(test.hash, test.password)
+			if err != test.wantErr {
+				t.Fatalf("err = %v, want %v", err, test.wantErr)
+			}
+
+			if got != test.want {
+				t.Errorf("Verify = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestHasherChainNeedsRehash(t *testing.T) {
+	chain := newTestHasherChain()
+	weakerParams := testArgon2Params
+	weakerParams.Memory = testArgon2Params.Memory / 2
+
+	tests := []struct {
+		name string
+		hash string
+		want bool
+	}{
+		{"preferred hasher and settings", mustHash(t, chain, "password"), false},
+		{"preferred hasher, weaker settings", mustHash(t, NewArgon2idHasher(weakerParams), "password"), true},
+		{"legacy hasher", mustHash(t, NewBcryptHasher(bcrypt.MinCost), "password"), true},
+		{"unknown format", "plain text", true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := chain.NeedsRehash(test.hash); got != test.want {
+				t.Errorf("NeedsRehash = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestBcryptHasherNeedsRehash(t *testing.T) {
+	hasher := NewBcryptHasher(bcrypt.MinCost + 1)
+
+	tests := []struct {
+		name string
+		hash string
+		want bool
+	}{
+		{"lower cost", mustHash(t, NewBcryptHasher(bcrypt.MinCost), "password"), true},
+		{"same cost", mustHash(t, hasher, "password"), false},
+		{"not a bcrypt hash", "plain text", true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := hasher.NeedsRehash(test.hash); got != test.want {
+				t.Errorf("NeedsRehash = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestLoginRehashesLegacyPasswords(t *testing.T) {
+	service, repository, _ := newTestService(newTestHasherChain())
+
+	legacyHash := mustHash(t, NewBcryptHasher(bcrypt.MinCost), "correct password")
+	user, err := repository.Save(User{Name: "Test", Email: "test@example.com", PasswordHash: legacyHash, Role: RoleUser})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = service.Login(LoginInput{Email: "test@example.com", Password: "wrong password", IPAddress: "10.0.0.1"})
+	if err != ErrInvalidCredentials {
+		t.Fatalf("err = %v, want ErrInvalidCredentials", err)
+	}
+
+	stored, _ := repository.FIndByID(user.ID)
+	if stored.PasswordHash != legacyHash {
+		t.Fatal("a failed login changed the stored hash")
+	}
+
+	_, err = service.Login(LoginInput{Email: "test@example.com", Password: "correct password", IPAddress: "10.0.0.1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stored, _ = repository.FIndByID(user.ID)
+	if !NewArgon2idHasher(testArgon2Params).Matches(stored.PasswordHash) {
+		t.Fatalf("PasswordHash = %q, want an argon2id hash", stored.PasswordHash)
+	}
+
+	_, err = service.Login(LoginInput{Email: "test@example.com", Password: "correct password", IPAddress: "10.0.0.1"})
+	if err != nil {
+		t.Errorf("login with the rehashed password: %v", err)
+	}
+}
@@ -8,10 +8,42 @@ type RegisterUserInput struct {
 }
 
 type LoginInput struct {
-	Email    string `json:"email" binding:"required,email"`
-	Password string `json:"password" binding:"required"`
+	Email     string `json:"email" binding:"required,email"`
+	Password  string `json:"password" binding:"required"`
+	IPAddress string
 }
 
 type CheckEmailInput struct {
 	Email string `json:"email" binding:"required,email"`
 }
+
+type ForgotPasswordInput struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+type ResetPasswordTokenInput struct {
+	Token string `uri:"token" binding:"required"`
+}
+
+type ResetPasswordInput struct {
+	Password string `json:"password" binding:"required,min=8"`
+}
+
+type VerifyEmailInput struct {
+	Token string `uri:"token" binding:"required"`
+}
+
+type GetUserDetailInput struct {
+	ID int `uri:"id" binding:"required"`
+}
+
+type UpdateUserInput struct {
+	Name       string `json:"name" binding:"required"`
+	Occupation string `json:"occupation" binding:"required"`
+	Email      string `json:"email" binding:"required,email"`
+}
+
+type ChangePasswordInput struct {
+	CurrentPassword string `json:"current_password" binding:"required"`
+	NewPassword     string `json:"new_password" binding:"required,min=8"`
+}
@@ -0,0 +1,22 @@
+package user
+
+import "gorm.io/gorm"
+
+// VerifyExistingUsers adds the verified_at column and marks every account
+// that existed before email verification as verified, since they were
+// never sent a link. It does nothing once the column exists, so it has to
+// run before AutoMigrate.
+func VerifyExistingUsers(db *gorm.DB) error {
+	if !db.Migrator().HasTable(&User{}) || db.Migrator().HasColumn(&User{}, "verified_at") {
+		return nil
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Migrator().AddColumn(&User{}, "VerifiedAt")
+		if err != nil {
+			return err
+		}
+
+		return tx.Exec("UPDATE users SET verified_at = coalesce(created_at, now())").Error
+	})
+}
@@ -1,12 +1,21 @@
 package user
 
-import "gorm.io/gorm"
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
 
 type Repository interface {
+	FindAll() ([]User, error)
 	Save(user User) (User, error)
 	FindByEmail(email string) (User, error)
 	FIndByID(ID int) (User, error)
 	Update(user User) (User, error)
+	SaveActionToken(token ActionToken) (ActionToken, error)
+	FindActionTokenByHash(purpose string, hash string) (ActionToken, error)
+	MarkActionTokenUsed(ID int) (bool, error)
+	MarkActionTokensUsedByUserID(userID int, purpose string) error
 }
 
 type repository struct {
@@ -17,6 +26,17 @@ func NewRepository(db *gorm.DB) *repository {
 	return &repository{db}
 }
 
+func (r *repository) FindAll() ([]User, error) {
+	var users []User
+
+	err := r.db.Order("id asc").Find(&users).Error
+	if err != nil {
+		return users, err
+	}
+
+	return users, nil
+}
+
 func (r *repository) Save(user User) (User, error) {
 	err := r.db.Create(&user).Error
 	if err != nil {
@@ -56,3 +76,43 @@ func (r *repository) Update(user User) (User, error) {
 
 	return user, nil
 }
+
+func (r *repository) SaveActionToken(token ActionToken) (ActionToken, error) {
+	err := r.db.Create(&token).Error
+	if err != nil {
+		return token, err
+	}
+
+	return token, nil
+}
+
+func (r *repository) FindActionTokenByHash(purpose string, hash string) (ActionToken, error) {
+	var token ActionToken
+
+	err := r.db.Where("purpose = ? AND token_hash = ?", purpose, hash).Find(&token).Error
+	if err != nil {
+		return token, err
+	}
+
+	return token, nil
+}
+
+// MarkActionTokenUsed reports false when the token was already used, so the
+// same link cannot be redeemed twice even by concurrent requests.
+func (r *repository) MarkActionTokenUsed(ID int) (bool, error) {
+	result := r.db.Model(&ActionToken{}).Where("id = ? AND used_at IS NULL", ID).Update("used_at", time.Now())
+	if result.Error != nil {
+		return false, result.Error
+	}
+
+	return result.RowsAffected == 1, nil
+}
+
+func (r *repository) MarkActionTokensUsedByUserID(userID int, purpose string) error {
+	err := r.db.Model(&ActionToken{}).Where("user_id = ? AND purpose = ? AND used_at IS NULL", userID, purpose).Update("used_at", time.Now()).Error
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -1,8 +1,22 @@
 package user
 
 import (
+	"bwastartup/helper"
+	"bwastartup/mailer"
 	"errors"
-	"golang.org/x/crypto/bcrypt"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	ErrInvalidActionToken = errors.New("invalid or expired token")
+	ErrUserBanned         = errors.New("account has been banned")
+	ErrEmailNotAvailable  = errors.New("email has been registered")
+	ErrWrongPassword      = errors.New("current password is wrong")
+	ErrInvalidCredentials = errors.New("invalid credentials")
 )
 
 type Service interface {
@@ -10,16 +24,38 @@ type Service interface {
 	Login(input LoginInput) (User, error)
 	IsEmailAvailable(input CheckEmailInput) (bool, error)
 	SaveAvatar(ID int, fileLocation string) (User, error)
+	UpdateUser(ID int, input UpdateUserInput) (User, error)
+	ChangePassword(ID int, input ChangePasswordInput) (User, error)
 	GetUserByID(ID int) (User, error)
-	//UploadToCloud(file *multipart.FileHeader, userId int) error
+	GetUsers() ([]User, error)
+	BanUser(input GetUserDetailInput) (User, error)
+	UnbanUser(input GetUserDetailInput) (User, error)
+	RequestPasswordReset(input ForgotPasswordInput) error
+	ResetPassword(inputToken ResetPasswordTokenInput, inputData ResetPasswordInput) (User, error)
+	SendEmailVerification(user User) error
+	VerifyEmail(input VerifyEmailInput) (User, error)
+}
+
+type Config struct {
+	AppURL               string
+	PasswordResetTTL     time.Duration
+	EmailVerificationTTL time.Duration
+	Throttle             ThrottleConfig
 }
 
 type service struct {
-	repository Repository
+	repository   Repository
+	hasher       PasswordHasher
+	attemptStore AttemptStore
+	mailer       mailer.Mailer
+	config       Config
+
+	dummyHashOnce sync.Once
+	dummyHash     string
 }
 
-func NewService(repository Repository) *service {
-	return &service{repository}
+func NewService(repository Repository, hasher PasswordHasher, attemptStore AttemptStore, mailer mailer.Mailer, config Config) *service {
+	return &service{repository: repository, hasher: hasher, attemptStore: attemptStore, mailer: mailer, config: config}
 }
 
 func (s *service) RegisterUser(input RegisterUserInput) (User, error) {
@@ -27,40 +63,90 @@ func (s *service) RegisterUser(input RegisterUserInput) (User, error) {
 	user.Name = input.Name
 	user.Email = input.Email
 	user.Occupation = input.Occupation
-	passwordHash, err := bcrypt.GenerateFromPassword([]byte(input.Password), bcrypt.MinCost)
+	passwordHash, err := s.hasher.Hash(input.Password)
 	if err != nil {
 		return user, err
 	}
 
-	user.PasswordHash = string(passwordHash)
-	user.Role = "user"
+	user.PasswordHash = passwordHash
+	user.Role = RoleUser
 
 	newUser, err := s.repository.Save(user)
 	if err != nil {
 		return newUser, err
 	}
 
+	// The account is usable without the email, and the user can ask for the
+	// link again, so a mail failure must not fail the registration.
+	_ = s.SendEmailVerification(newUser)
+
 	return newUser, nil
 }
 
+// Login answers ErrInvalidCredentials for both an unknown email and a wrong
+// password, and spends the same hashing time on both, so it cannot be used
+// to find out which emails are registered.
 func (s *service) Login(input LoginInput) (User, error) {
 	email := input.Email
 	password := input.Password
 
+	emailIdentifier := "email:" + strings.ToLower(email)
+	ipIdentifier := "ip:" + input.IPAddress
+
+	err := s.checkLockout(emailIdentifier, ipIdentifier)
+	if err != nil {
+		return User{}, err
+	}
+
 	user, err := s.repository.FindByEmail(email)
 	if err != nil {
-		return user, err
+		return User{}, err
 	}
 
+	passwordHash := user.PasswordHash
 	if user.ID == 0 {
-		return user, errors.New("User Not Found")
+		passwordHash = s.getDummyHash()
 	}
 
-	err = bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password))
+	isValid, err := s.hasher.Verify(passwordHash, password)
+	if err != nil || !isValid || user.ID == 0 {
+		err := s.recordLoginFailure(emailIdentifier, s.config.Throttle.MaxAttempts)
+		if err != nil {
+			return User{}, err
+		}
+
+		err = s.recordLoginFailure(ipIdentifier, s.config.Throttle.MaxAttemptsPerIP)
+		if err != nil {
+			return User{}, err
+		}
+
+		return User{}, ErrInvalidCredentials
+	}
+
+	err = s.attemptStore.Reset(emailIdentifier)
 	if err != nil {
 		return user, err
 	}
 
+	if user.BannedAt != nil {
+		return user, ErrUserBanned
+	}
+
+	// This is the only time the plain password is known, so hashes made
+	// with an older algorithm or cost are upgraded here. Failing to save the
+	// new hash is not a reason to refuse the login.
+	if s.hasher.NeedsRehash(user.PasswordHash) {
+		passwordHash, err := s.hasher.Hash(password)
+		if err == nil {
+			user.PasswordHash = passwordHash
+
+			updatedUser, err := s.repository.Update(user)
+			if err == nil {
+				user = updatedUser
+			}
+		}
+	}
+
 	return user, nil
 }
 
@@ -95,6 +181,94 @@ func (s *service) SaveAvatar(ID int, fileLocation string) (User, error) {
 	return updatedUser, nil
 }
 
+// UpdateUser changes the profile fields. A new email has to be available
+// and verified again before the account counts as verified.
+func (s *service) UpdateUser(ID int, input UpdateUserInput) (User, error) {
+	user, err := s.GetUserByID(ID)
+	if err != nil {
+		return user, err
+	}
+
+	isEmailChanged := input.Email != user.Email
+	if isEmailChanged {
+		isEmailAvailable, err := s.IsEmailAvailable(CheckEmailInput{Email: input.Email})
+		if err != nil {
+			return user, err
+		}
+
+		if !isEmailAvailable {
+			return user, ErrEmailNotAvailable
+		}
+
+		user.Email = input.Email
+		user.VerifiedAt = nil
+	}
+
+	user.Name = input.Name
+	user.Occupation = input.Occupation
+
+	updatedUser, err := s.repository.Update(user)
+	if err != nil {
+		return updatedUser, err
+	}
+
+	if isEmailChanged {
+		// Same as on registration: the user can request the link again.
+		_ = s.SendEmailVerification(updatedUser)
+	}
+
+	return updatedUser, nil
+}
+
+// ChangePassword counts a wrong current password as a failed login of the
+// account, so a stolen session cannot be used to guess the password.
+func (s *service) ChangePassword(ID int, input ChangePasswordInput) (User, error) {
+	user, err := s.GetUserByID(ID)
+	if err != nil {
+		return user, err
+	}
+
+	emailIdentifier := "email:" + strings.ToLower(user.Email)
+
+	err = s.checkLockout(emailIdentifier)
+	if err != nil {
+		return user, err
+	}
+
+	isValid, err := s.hasher.Verify(user.PasswordHash, input.CurrentPassword)
+	if err != nil {
+		return user, err
+	}
+
+	if !isValid {
+		err := s.recordLoginFailure(emailIdentifier, s.config.Throttle.MaxAttempts)
+		if err != nil {
+			return user, err
+		}
+
+		return user, ErrWrongPassword
+	}
+
+	err = s.attemptStore.Reset(emailIdentifier)
+	if err != nil {
+		return user, err
+	}
+
+	passwordHash, err := s.hasher.Hash(input.NewPassword)
+	if err != nil {
+		return user, err
+	}
+
+	user.PasswordHash = passwordHash
+
+	updatedUser, err := s.repository.Update(user)
+	if err != nil {
+		return updatedUser, err
+	}
+
+	return updatedUser, nil
+}
+
 func (s *service) GetUserByID(ID int) (User, error) {
 	user, err := s.repository.FIndByID(ID)
 	if err != nil {
@@ -107,44 +281,202 @@ func (s *service) GetUserByID(ID int) (User, error) {
 	return user, nil
 }
 
-//func (s *service) UploadToCloud(file *multipart.FileHeader, userId int) error {
-//	bucket := "donation_alert"
-//	object := file.Filename
-//	pathName := fmt.Sprintf("%d-%s", userId, file.Filename)
-//	ctx := context.Background()
-//	client, err := storage.NewClient(ctx)
-//	if err != nil {
-//		return err
-//	}
-//	defer client.Close()
-//
-//	newFile, err := file.Open()
-//	if err != nil {
-//		return err
-//	}
-//	defer newFile.Close()
-//	var b []byte
-//
-//	newFile.Read(b)
-//	buf := bytes.NewBuffer(b)
-//
-//	ctx, cancel := context.WithTimeout(ctx, time.Second*50)
-//	defer cancel()
-//
-//	// Upload an object with storage2.Writer.
-//	wc := client.Bucket(bucket).Object(object).NewWriter(ctx)
-//	wc.ChunkSize = 0 // note retries are not supported for chunk size 0.
-//
-//	if _, err = io.Copy(wc, buf); err != nil {
-//		return err
-//	}
-//	// Data can continue to be added to the file until the writer is closed.
-//	if err := wc.Close(); err != nil {
-//		return err
-//	}
-//	acl := client.Bucket(bucket).Object(object).ACL()
-//	if err := acl.Set(ctx, storage.AllUsers, storage.RoleReader); err != nil {
-//		return err
-//	}
-//	return nil
-//}
+func (s *service) GetUsers() ([]User, error) {
+	users, err := s.repository.FindAll()
+	if err != nil {
+		return users, err
+	}
+
+	return users, nil
+}
+
+func (s *service) BanUser(input GetUserDetailInput) (User, error) {
+	user, err := s.GetUserByID(input.ID)
+	if err != nil {
+		return user, err
+	}
+
+	if user.Role == RoleAdmin {
+		return user, errors.New("admins cannot be banned")
+	}
+
+	now := time.Now()
+	user.BannedAt = &now
+
+	updatedUser, err := s.repository.Update(user)
+	if err != nil {
+		return updatedUser, err
+	}
+
+	return updatedUser, nil
+}
+
+func (s *service) UnbanUser(input GetUserDetailInput) (User, error) {
+	user, err := s.GetUserByID(input.ID)
+	if err != nil {
+		return user, err
+	}
+
+	user.BannedAt = nil
+
+	updatedUser, err := s.repository.Update(user)
+	if err != nil {
+		return updatedUser, err
+	}
+
+	return updatedUser, nil
+}
+
+// RequestPasswordReset mails a reset link when the email belongs to an
+// account. Unknown emails are not reported, and a failed mail is only
+// logged, so the endpoint cannot be used to find out who is registered.
+func (s *service) RequestPasswordReset(input ForgotPasswordInput) error {
+	user, err := s.repository.FindByEmail(input.Email)
+	if err != nil {
+		return err
+	}
+
+	if user.ID == 0 {
+		return nil
+	}
+
+	err = s.repository.MarkActionTokensUsedByUserID(user.ID, PurposePasswordReset)
+	if err != nil {
+		return err
+	}
+
+	token, err := s.createActionToken(user.ID, PurposePasswordReset, s.config.PasswordResetTTL)
+	if err != nil {
+		return err
+	}
+
+	message := mailer.Message{}
+	message.To = user.Email
+	message.Subject = "Reset your BWA Startup password"
+	message.Body = fmt.Sprintf("Hi %s,\n\nWe received a request to reset your password. Open the link below to choose a new one:\n\n%s/reset-password/%s\n\nThe link expires in %s. If you did not request a reset, you can ignore this email.\n", user.Name, s.config.AppURL, token, s.config.PasswordResetTTL)
+
+	err = s.mailer.Send(message)
+	if err != nil {
+		log.Println("send password reset email:", err.Error())
+	}
+
+	return nil
+}
+
+func (s *service) ResetPassword(inputToken ResetPasswordTokenInput, inputData ResetPasswordInput) (User, error) {
+	userID, err := s.redeemActionToken(PurposePasswordReset, inputToken.Token)
+	if err != nil {
+		return User{}, err
+	}
+
+	user, err := s.GetUserByID(userID)
+	if err != nil {
+		return user, err
+	}
+
+	passwordHash, err := s.hasher.Hash(inputData.Password)
+	if err != nil {
+		return user, err
+	}
+
+	user.PasswordHash = passwordHash
+
+	updatedUser, err := s.repository.Update(user)
+	if err != nil {
+		return updatedUser, err
+	}
+
+	return updatedUser, nil
+}
+
+func (s *service) SendEmailVerification(user User) error {
+	if user.VerifiedAt != nil {
+		return errors.New("email is already verified")
+	}
+
+	err := s.repository.MarkActionTokensUsedByUserID(user.ID, PurposeEmailVerification)
+	if err != nil {
+		return err
+	}
+
+	token, err := s.createActionToken(user.ID, PurposeEmailVerification, s.config.EmailVerificationTTL)
+	if err != nil {
+		return err
+	}
+
+	message := mailer.Message{}
+	message.To = user.Email
+	message.Subject = "Verify your BWA Startup email"
+	message.Body = fmt.Sprintf("Hi %s,\n\nPlease confirm your email address by opening the link below:\n\n%s/verify-email/%s\n\nThe link expires in %s.\n", user.Name, s.config.AppURL, token, s.config.EmailVerificationTTL)
+
+	err = s.mailer.Send(message)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (s *service) VerifyEmail(input VerifyEmailInput) (User, error) {
+	userID, err := s.redeemActionToken(PurposeEmailVerification, input.Token)
+	if err != nil {
+		return User{}, err
+	}
+
+	user, err := s.GetUserByID(userID)
+	if err != nil {
+		return user, err
+	}
+
+	now := time.Now()
+	user.VerifiedAt = &now
+
+	updatedUser, err := s.repository.Update(user)
+	if err != nil {
+		return updatedUser, err
+	}
+
+	return updatedUser, nil
+}
+
+func (s *service) createActionToken(userID int, purpose string, ttl time.Duration) (string, error) {
+	token, err := helper.RandomToken(32)
+	if err != nil {
+		return "", err
+	}
+
+	actionToken := ActionToken{}
+	actionToken.UserID = userID
+	actionToken.Purpose = purpose
+	actionToken.TokenHash = helper.HashToken(token)
+	actionToken.ExpiresAt = time.Now().Add(ttl)
+
+	_, err = s.repository.SaveActionToken(actionToken)
+	if err != nil {
+		return "", err
+	}
+
+	return token, nil
+}
+
+func (s *service) redeemActionToken(purpose string, token string) (int, error) {
+	actionToken, err := s.repository.FindActionTokenByHash(purpose, helper.HashToken(token))
+	if err != nil {
+		return 0, err
+	}
+
+	if actionToken.ID == 0 || actionToken.UsedAt != nil || time.Now().After(actionToken.ExpiresAt) {
+		return 0, ErrInvalidActionToken
+	}
+
+	isUsed, err := s.repository.MarkActionTokenUsed(actionToken.ID)
+	if err != nil {
+		return 0, err
+	}
+
+	if !isUsed {
+		return 0, ErrInvalidActionToken
+	}
+
+	return actionToken.UserID, nil
+}
@@ -0,0 +1,170 @@
+package user
+
+import (
+	"bwastartup/mailer"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// memoryRepository keeps users in memory. Methods the tests do not use fall
+// through to the embedded nil Repository.
+type memoryRepository struct {
+	Repository
+	users  []User
+	tokens []ActionToken
+}
+
+func (r *memoryRepository) Save(user User) (User, error) {
+	user.ID = len(r.users) + 1
+	r.users = append(r.users, user)
+
+	return user, nil
+}
+
+func (r *memoryRepository) FindByEmail(email string) (User, error) {
+	for _, user := range r.users {
+		if strings.EqualFold(user.Email, email) {
+			return user, nil
+		}
+	}
+
+	return User{}, nil
+}
+
+func (r *memoryRepository) FIndByID(ID int) (User, error) {
+	for _, user := range r.users {
+		if user.ID == ID {
+			return user, nil
+		}
+	}
+
+	return User{}, nil
+}
+
+func (r *memoryRepository) Update(user User) (User, error) {
+	for i := range r.users {
+		if r.users[i].ID == user.ID {
+			r.users[i] = user
+		}
+	}
+
+	return user, nil
+}
+
+func (r *memoryRepository) SaveActionToken(token ActionToken) (ActionToken, error) {
+	token.ID = len(r.tokens) + 1
+	r.tokens = append(r.tokens, token)
+
+	return token, nil
+}
+
+func (r *memoryRepository) MarkActionTokensUsedByUserID(userID int, purpose string) error {
+	now := time.Now()
+	for i := range r.tokens {
+		if r.tokens[i].UserID == userID && r.tokens[i].Purpose == purpose && r.tokens[i].UsedAt == nil {
+			r.tokens[i].UsedAt = &now
+		}
+	}
+
+	return nil
+}
+
+var testThrottle = ThrottleConfig{
+	MaxAttempts:        3,
+	MaxAttemptsPerIP:   10,
+	Window:             time.Hour,
+	LockoutDuration:    time.Minute,
+	MaxLockoutDuration: 10 * time.Minute,
+}
+
+func newTestService(hasher PasswordHasher) (*service, *memoryRepository, *memoryAttemptStore) {
+	repository := &memoryRepository{}
+	attemptStore := NewMemoryAttemptStore()
+	service := NewService(repository, hasher, attemptStore, mailer.NewMemoryMailer(), Config{Throttle: testThrottle})
+
+	return service, repository, attemptStore
+}
+
+func newTestUser(t *testing.T, service *service, repository *memoryRepository, password string) User {
+	passwordHash, err := service.hasher.Hash(password)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	user, err := repository.Save(User{Name: "Test", Email: "test@example.com", PasswordHash: passwordHash, Role: RoleUser})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return user
+}
+
+func newTestBcryptHasher() *bcryptHasher {
+	return NewBcryptHasher(bcrypt.MinCost)
+}
+
+type failingMailer struct{}
+
+func (m failingMailer) Send(message mailer.Message) error {
+	return errors.New("smtp unavailable")
+}
+
+func TestRequestPasswordResetAnswersTheSameForEveryEmail(t *testing.T) {
+	tests := []struct {
+		name   string
+		email  string
+		mailer mailer.Mailer
+	}{
+		{"registered email", "test@example.com", mailer.NewMemoryMailer()},
+		{"unknown email", "nobody@example.com", mailer.NewMemoryMailer()},
+		{"registered email, mail fails", "test@example.com", failingMailer{}},
+		{"unknown email, mail fails", "nobody@example.com", failingMailer{}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			service, repository, _ := newTestService(newTestBcryptHasher())
+			service.mailer = test.mailer
+			newTestUser(t, service, repository, "correct password")
+
+			err := service.RequestPasswordReset(ForgotPasswordInput{Email: test.email})
+			if err != nil {
+				t.Errorf("err = %v, want nil", err)
+			}
+		})
+	}
+}
+
+func TestChangePassword(t *testing.T) {
+	tests := []struct {
+		name            string
+		currentPassword string
+		wantErr         error
+		wantPassword    string
+	}{
+		{"right current password", "correct password", nil, "new password"},
+		{"wrong current password", "wrong password", ErrWrongPassword, "correct password"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			service, repository, _ := newTestService(newTestBcryptHasher())
+			user := newTestUser(t, service, repository, "correct password")
+
+			_, err := service.ChangePassword(user.ID, ChangePasswordInput{CurrentPassword: test.currentPassword, NewPassword: "new password"})
+			if err != test.wantErr {
+				t.Fatalf("err = %v, want %v", err, test.wantErr)
+			}
+
+			stored, _ := repository.FIndByID(user.ID)
+			isValid, err := service.hasher.Verify(stored.PasswordHash, test.wantPassword)
+			if err != nil || !isValid {
+				t.Errorf("stored hash does not match %q", test.wantPassword)
+			}
+		})
+	}
+}
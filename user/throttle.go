@@ -0,0 +1,73 @@
+package user
+
+import (
+	"math"
+	"time"
+)
+
+type ThrottleConfig struct {
+	// MaxAttempts failures of one account, or MaxAttemptsPerIP failures from
+	// one address, within the sliding Window lock that identifier for
+	// LockoutDuration. Every further failure doubles the lockout, up to
+	// MaxLockoutDuration. Window has to be longer than MaxLockoutDuration,
+	// or the failures before a long lockout expire during it.
+	MaxAttempts        int
+	MaxAttemptsPerIP   int
+	Window             time.Duration
+	LockoutDuration    time.Duration
+	MaxLockoutDuration time.Duration
+}
+
+type LockoutError struct {
+	Until time.Time
+}
+
+func (e *LockoutError) Error() string {
+	return "too many failed login attempts"
+}
+
+func (s *service) checkLockout(identifiers ...string) error {
+	now := time.Now()
+
+	for _, identifier := range identifiers {
+		attempt, err := s.attemptStore.Get(identifier)
+		if err != nil {
+			return err
+		}
+
+		if attempt.LockedUntil != nil && attempt.LockedUntil.After(now) {
+			return &LockoutError{Until: *attempt.LockedUntil}
+		}
+	}
+
+	return nil
+}
+
+func (s *service) recordLoginFailure(identifier string, maxAttempts int) error {
+	attempt, err := s.attemptStore.RecordFailure(identifier, s.config.Throttle.Window)
+	if err != nil {
+		return err
+	}
+
+	if attempt.Failures < maxAttempts {
+		return nil
+	}
+
+	exponent := float64(attempt.Failures - maxAttempts)
+	lockout := time.Duration(float64(s.config.Throttle.LockoutDuration) * math.Pow(2, exponent))
+	if lockout <= 0 || lockout > s.config.Throttle.MaxLockoutDuration {
+		lockout = s.config.Throttle.MaxLockoutDuration
+	}
+
+	return s.attemptStore.Lock(identifier, time.Now().Add(lockout))
+}
+
+// getDummyHash returns a hash in the preferred format to verify against when
+// the account does not exist.
+func (s *service) getDummyHash() string {
+	s.dummyHashOnce.Do(func() {
+		s.dummyHash, _ = s.hasher.Hash("dummy password for unknown accounts")
+	})
+
+	return s.dummyHash
+}
@@ -0,0 +1,165 @@
+package user
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestMemoryAttemptStoreCountsFailuresInSlidingWindow(t *testing.T) {
+	now := time.Now()
+
+	tests := []struct {
+		name     string
+		previous []time.Time
+		want     int
+	}{
+		{"first failure", nil, 1},
+		{"recent failures count", []time.Time{now.Add(-50 * time.Minute), now.Add(-time.Minute)}, 3},
+		{"only failures older than the window expire", []time.Time{now.Add(-2 * time.Hour), now.Add(-50 * time.Minute)}, 2},
+		{"all failures expired", []time.Time{now.Add(-3 * time.Hour), now.Add(-2 * time.Hour)}, 1},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			store := NewMemoryAttemptStore()
+			store.failures["email:test@example.com"] = test.previous
+
+			attempt, err := store.RecordFailure("email:test@example.com", time.Hour)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if attempt.Failures != test.want {
+				t.Errorf("Failures = %d, want %d", attempt.Failures, test.want)
+			}
+		})
+	}
+}
+
+func TestRecordLoginFailureLockout(t *testing.T) {
+	tests := []struct {
+		name     string
+		failures int
+		want     time.Duration
+	}{
+		{"below the limit", 1, 0},
+		{"at the limit", 2, time.Minute},
+		{"doubles after the limit", 3, 2 * time.Minute},
+		{"doubles again", 4, 4 * time.Minute},
+		{"capped", 10, 10 * time.Minute},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			service, _, attemptStore := newTestService(newTestBcryptHasher())
+
+			previous := make([]time.Time, test.failures)
+			for i := range previous {
+				previous[i] = time.Now().Add(-time.Minute)
+			}
+			attemptStore.failures["email:test@example.com"] = previous
+
+			before := time.Now()
+			err := service.recordLoginFailure("email:test@example.com", testThrottle.MaxAttempts)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			attempt, _ := attemptStore.Get("email:test@example.com")
+			if test.want == 0 {
+				if attempt.LockedUntil != nil {
+					t.Fatalf("LockedUntil = %v, want not locked", attempt.LockedUntil)
+				}
+				return
+			}
+
+			if attempt.LockedUntil == nil {
+				t.Fatal("LockedUntil = nil, want locked")
+			}
+
+			lockout := attempt.LockedUntil.Sub(before)
+			if lockout < test.want || lockout > test.want+time.Second {
+				t.Errorf("lockout = %s, want %s", lockout, test.want)
+			}
+		})
+	}
+}
+
+func TestLoginLocksAccountAfterMaxAttempts(t *testing.T) {
+	service, repository, _ := newTestService(newTestBcryptHasher())
+	newTestUser(t, service, repository, "correct password")
+
+	for i := 0; i < testThrottle.MaxAttempts; i++ {
+		_, err := service.Login(LoginInput{Email: "test@example.com", Password: "wrong password", IPAddress: "10.0.0.1"})
+		if err != ErrInvalidCredentials {
+			t.Fatalf("attempt %d: err = %v, want ErrInvalidCredentials", i+1, err)
+		}
+	}
+
+	_, err := service.Login(LoginInput{Email: "TEST@example.com", Password: "correct password", IPAddress: "10.0.0.2"})
+	var lockoutError *LockoutError
+	if !errors.As(err, &lockoutError) {
+		t.Fatalf("err = %v, want LockoutError", err)
+	}
+}
+
+func TestLoginThrottlesUnknownAccounts(t *testing.T) {
+	service, _, _ := newTestService(newTestBcryptHasher())
+
+	for i := 0; i < testThrottle.MaxAttempts; i++ {
+		_, err := service.Login(LoginInput{Email: "nobody@example.com", Password: "password", IPAddress: "10.0.0.1"})
+		if err != ErrInvalidCredentials {
+			t.Fatalf("attempt %d: err = %v, want ErrInvalidCredentials", i+1, err)
+		}
+	}
+
+	_, err := service.Login(LoginInput{Email: "nobody@example.com", Password: "password", IPAddress: "10.0.0.1"})
+	var lockoutError *LockoutError
+	if !errors.As(err, &lockoutError) {
+		t.Fatalf("err = %v, want LockoutError", err)
+	}
+}
+
+func TestLoginResetsFailuresOnSuccess(t *testing.T) {
+	service, repository, attemptStore := newTestService(newTestBcryptHasher())
+	newTestUser(t, service, repository, "correct password")
+
+	_, err := service.Login(LoginInput{Email: "test@example.com", Password: "wrong password", IPAddress: "10.0.0.1"})
+	if err != ErrInvalidCredentials {
+		t.Fatalf("err = %v, want ErrInvalidCredentials", err)
+	}
+
+	_, err = service.Login(LoginInput{Email: "test@example.com", Password: "correct password", IPAddress: "10.0.0.1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	attempt, _ := attemptStore.Get("email:test@example.com")
+	if attempt.Failures != 0 {
+		t.Errorf("Failures = %d, want 0", attempt.Failures)
+	}
+}
+
+func TestChangePasswordIsThrottled(t *testing.T) {
+	service, repository, _ := newTestService(newTestBcryptHasher())
+	user := newTestUser(t, service, repository, "correct password")
+
+	for i := 0; i < testThrottle.MaxAttempts; i++ {
+		_, err := service.ChangePassword(user.ID, ChangePasswordInput{CurrentPassword: "wrong password", NewPassword: "new password"})
+		if err != ErrWrongPassword {
+			t.Fatalf("attempt %d: err = %v, want ErrWrongPassword", i+1, err)
+		}
+	}
+
+	_, err := service.ChangePassword(user.ID, ChangePasswordInput{CurrentPassword: "correct password", NewPassword: "new password"})
+	var lockoutError *LockoutError
+	if !errors.As(err, &lockoutError) {
+		t.Fatalf("err = %v, want LockoutError", err)
+	}
+
+	_, err = service.Login(LoginInput{Email: "test@example.com", Password: "correct password", IPAddress: "10.0.0.1"})
+	if !errors.As(err, &lockoutError) {
+		t.Fatalf("Login err = %v, want LockoutError", err)
+	}
+}